@@ -0,0 +1,196 @@
+package goredis
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/hecigo/goutils"
+)
+
+// The state of the circuit breaker of a connection, see [Config].BreakerThreshold.
+type BreakerState string
+
+const (
+	BreakerState_Disabled BreakerState = ""          // the connection has no circuit breaker
+	BreakerState_Closed   BreakerState = "closed"    // the commands are sent to the server
+	BreakerState_Open     BreakerState = "open"      // the commands fail fast with [ErrCircuitOpen]
+	BreakerState_HalfOpen BreakerState = "half-open" // a few probe commands are sent to check the server is back
+)
+
+// The circuit breaker of a connection. It opens after [Config].BreakerThreshold consecutive failures,
+// then lets [Config].BreakerProbes commands through after [Config].BreakerOpenDuration.
+// It closes again if all the probes succeed, or opens again on the first failing probe.
+type breaker struct {
+	name         string
+	threshold    int
+	openDuration time.Duration
+	probes       int
+
+	mu        sync.Mutex
+	state     BreakerState
+	failures  int       // consecutive failures while closed
+	openedAt  time.Time // when the breaker opened
+	inflight  int       // probes in flight while half-open
+	succeeded int       // succeeded probes while half-open
+}
+
+// Returns the circuit breaker of the connection, or nil if it is disabled.
+func (cfg *Config) newBreaker() *breaker {
+	if cfg.BreakerThreshold <= 0 {
+		return nil
+	}
+	return &breaker{
+		name:         cfg.ConnectionName,
+		threshold:    cfg.BreakerThreshold,
+		openDuration: cfg.BreakerOpenDuration,
+		probes:       cfg.BreakerProbes,
+		state:        BreakerState_Closed,
+	}
+}
+
+// Returns the current state, an open breaker is reported half-open once its open duration passed.
+func (b *breaker) State() BreakerState {
+	if b == nil {
+		return BreakerState_Disabled
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state == BreakerState_Open && time.Since(b.openedAt) >= b.openDuration {
+		return BreakerState_HalfOpen
+	}
+	return b.state
+}
+
+// Check the command is allowed. probe is true if the command is a probe of the half-open breaker.
+func (b *breaker) allow() (probe bool, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case BreakerState_Open:
+		if wait := b.openDuration - time.Since(b.openedAt); wait > 0 {
+			return false, ErrCircuitOpen{Name: b.name, RetryAfter: wait}
+		}
+		b.state = BreakerState_HalfOpen
+		b.inflight = 0
+		b.succeeded = 0
+		goutils.Printf("redis[%s]: circuit breaker is half-open, probing the server", b.name)
+		fallthrough
+	case BreakerState_HalfOpen:
+		if b.inflight >= b.probes {
+			return false, ErrCircuitOpen{Name: b.name}
+		}
+		b.inflight++
+		return true, nil
+	default:
+		return false, nil
+	}
+}
+
+// Record the result of an allowed command, ctx is the context of the caller.
+func (b *breaker) done(ctx context.Context, probe bool, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	failed := isBreakerFailure(ctx, err)
+	switch {
+	case probe && b.state == BreakerState_HalfOpen:
+		b.inflight--
+		if failed {
+			b.open(err)
+			return
+		}
+		b.succeeded++
+		if b.succeeded >= b.probes {
+			b.state = BreakerState_Closed
+			b.failures = 0
+			goutils.Printf("redis[%s]: circuit breaker is closed", b.name)
+		}
+	case !probe && b.state == BreakerState_Closed:
+		if !failed {
+			b.failures = 0
+			return
+		}
+		b.failures++
+		if b.failures >= b.threshold {
+			b.open(err)
+		}
+	}
+	// otherwise the command was allowed before the state changed, its result is outdated
+}
+
+// Open the breaker, the caller must hold the lock.
+func (b *breaker) open(err error) {
+	b.state = BreakerState_Open
+	b.openedAt = time.Now()
+	b.failures = 0
+	goutils.Warnf("redis[%s]: circuit breaker is open for %s: %s", b.name, b.openDuration, err)
+}
+
+// Returns true if the error means the server is degraded, i.e. a network error such as a refused connection,
+// or a timeout of the client itself. The replies of the server, e.g. [redis.Nil] or WRONGTYPE,
+// and the cancellations and deadlines of the caller's ctx are not failures.
+func isBreakerFailure(ctx context.Context, err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		// the deadline of the caller is also set on the connection, so its timeouts are not the server's
+		return !netErr.Timeout() || ctx == nil || ctx.Err() == nil
+	}
+	return errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// The context key to pass the probe flag from BeforeProcess to AfterProcess.
+type breakerProbeKey struct{}
+
+// The [redis.Hook] to fail fast while the breaker is open. It runs before the other built-in hooks,
+// so that the rejected commands are not traced.
+type breakerHook struct {
+	breaker *breaker
+}
+
+func (h breakerHook) BeforeProcess(ctx context.Context, cmd redis.Cmder) (context.Context, error) {
+	probe, err := h.breaker.allow()
+	if err != nil {
+		return ctx, err
+	}
+	return context.WithValue(ctx, breakerProbeKey{}, probe), nil
+}
+
+func (h breakerHook) AfterProcess(ctx context.Context, cmd redis.Cmder) error {
+	h.after(ctx, cmd.Err())
+	return nil
+}
+
+func (h breakerHook) BeforeProcessPipeline(ctx context.Context, cmds []redis.Cmder) (context.Context, error) {
+	return h.BeforeProcess(ctx, nil)
+}
+
+func (h breakerHook) AfterProcessPipeline(ctx context.Context, cmds []redis.Cmder) error {
+	var err error
+	for _, cmd := range cmds {
+		if isBreakerFailure(ctx, cmd.Err()) {
+			err = cmd.Err()
+			break
+		}
+	}
+	h.after(ctx, err)
+	return nil
+}
+
+// Record the result, unless the command was rejected by the breaker itself.
+func (h breakerHook) after(ctx context.Context, err error) {
+	probe, ok := ctx.Value(breakerProbeKey{}).(bool)
+	if !ok {
+		return
+	}
+	h.breaker.done(ctx, probe, err)
+}
@@ -0,0 +1,195 @@
+package goredis_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/hecigo/goredis"
+	"github.com/hecigo/goutils"
+	. "gopkg.in/check.v1"
+)
+
+type BreakerSuite struct{}
+
+var _ = Suite(&BreakerSuite{})
+
+func (s *BreakerSuite) SetUpSuite(c *C) {
+	fmt.Println("SetUpSuite > BreakerSuite")
+	goutils.QuickLoad()
+}
+
+func (s *BreakerSuite) TearDownTest(c *C) {
+	goredis.Close("breaker")
+}
+
+// Test the breaker opens after the failures, fails fast, then closes after a succeeded probe
+func (s *BreakerSuite) TestBreaker(c *C) {
+	fault := &faultHook{}
+	c.Assert(goredis.OpenWithConfig(&goredis.Config{
+		ConnectionName:      "breaker",
+		KeyPrefix:           "test-breaker",
+		BreakerThreshold:    2,
+		BreakerOpenDuration: 50 * time.Millisecond,
+		Hooks:               []redis.Hook{fault},
+	}), IsNil)
+	ctx := goredis.WithConnection(context.Background(), "breaker")
+
+	// the replies of the server are not failures
+	for i := 0; i < 3; i++ {
+		v, err := goredis.Get[string](ctx, "test_breaker_missing")
+		c.Assert(err, IsNil)
+		c.Assert(v, IsNil)
+	}
+	c.Assert(stateOf(c), Equals, goredis.BreakerState_Closed)
+
+	fault.failing.Store(true)
+	for i := 0; i < 2; i++ {
+		_, err := goredis.Get[string](ctx, "test_breaker")
+		c.Assert(err, ErrorMatches, "injected fault")
+	}
+	c.Assert(stateOf(c), Equals, goredis.BreakerState_Open)
+
+	// fails fast without reaching the hooks after the breaker
+	calls := fault.calls.Load()
+	_, err := goredis.Get[string](ctx, "test_breaker")
+	var circuitErr goredis.ErrCircuitOpen
+	c.Assert(errors.As(err, &circuitErr), Equals, true)
+	c.Assert(circuitErr.Name, Equals, "breaker")
+	c.Assert(circuitErr.RetryAfter > 0, Equals, true)
+	c.Assert(fault.calls.Load(), Equals, calls)
+
+	// a failed probe opens it again
+	time.Sleep(60 * time.Millisecond)
+	c.Assert(stateOf(c), Equals, goredis.BreakerState_HalfOpen)
+	c.Assert(goredis.Set(ctx, "test_breaker", "value"), ErrorMatches, "injected fault")
+	c.Assert(stateOf(c), Equals, goredis.BreakerState_Open)
+
+	// a succeeded probe closes it
+	time.Sleep(60 * time.Millisecond)
+	fault.failing.Store(false)
+	c.Assert(goredis.Set(ctx, "test_breaker", "value"), IsNil)
+	c.Assert(stateOf(c), Equals, goredis.BreakerState_Closed)
+}
+
+// Test the deadlines and cancellations of the caller are not failures of the server
+func (s *BreakerSuite) TestCallerDeadline(c *C) {
+	fault := &faultHook{}
+	c.Assert(goredis.OpenWithConfig(&goredis.Config{
+		ConnectionName:      "breaker",
+		KeyPrefix:           "test-breaker",
+		BreakerThreshold:    2,
+		BreakerOpenDuration: time.Minute,
+		Hooks:               []redis.Hook{fault},
+	}), IsNil)
+	ctx := goredis.WithConnection(context.Background(), "breaker")
+
+	// the server is slower than the deadline of the caller
+	for i := 0; i < 3; i++ {
+		deadline, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+		_, err := goredis.Client(ctx).BLPop(deadline, time.Second, "test_breaker_list").Result()
+		cancel()
+		c.Assert(err, NotNil)
+	}
+	c.Assert(stateOf(c), Equals, goredis.BreakerState_Closed)
+
+	// the timeouts after the caller gave up are not counted either
+	fault.failing.Store(true)
+	for i := 0; i < 3; i++ {
+		deadline, cancel := context.WithTimeout(ctx, time.Millisecond)
+		<-deadline.Done()
+		_, err := goredis.Get[string](deadline, "test_breaker")
+		c.Assert(err, NotNil)
+		canceled, cancel2 := context.WithCancel(ctx)
+		cancel2()
+		_, err = goredis.Get[string](canceled, "test_breaker")
+		c.Assert(err, NotNil)
+		cancel()
+	}
+	c.Assert(stateOf(c), Equals, goredis.BreakerState_Closed)
+
+	// the same timeouts open it while the caller still waits
+	for i := 0; i < 2; i++ {
+		_, err := goredis.Get[string](ctx, "test_breaker")
+		c.Assert(err, ErrorMatches, "injected fault")
+	}
+	c.Assert(stateOf(c), Equals, goredis.BreakerState_Open)
+}
+
+// Test the state of the breaker is reported by the health check
+func (s *BreakerSuite) TestHealth(c *C) {
+	c.Assert(goredis.OpenWithConfig(&goredis.Config{
+		ConnectionName:      "breaker",
+		Addresses:           []string{"localhost:1"},
+		KeyPrefix:           "test-breaker",
+		MaxRetries:          -1,
+		BreakerThreshold:    1,
+		BreakerOpenDuration: time.Minute,
+	}), IsNil)
+
+	c.Assert(goredis.Ping(context.Background(), "breaker"), NotNil)
+	c.Assert(goredis.Ping(context.Background(), "breaker"), FitsTypeOf, goredis.ErrCircuitOpen{})
+
+	for _, status := range goredis.HealthCheck(context.Background()) {
+		if status.Name == "breaker" {
+			c.Assert(status.Healthy, Equals, false)
+			c.Assert(status.Breaker, Equals, goredis.BreakerState_Open)
+			return
+		}
+	}
+	c.Fatal("the connection is not checked")
+}
+
+// Test the breaker is disabled by default, and its settings are validated
+func (s *BreakerSuite) TestDisabled(c *C) {
+	c.Assert(goredis.OpenWithConfig(&goredis.Config{ConnectionName: "breaker", KeyPrefix: "test-breaker"}), IsNil)
+	c.Assert(stateOf(c), Equals, goredis.BreakerState_Disabled)
+
+	_, err := goredis.CircuitBreakerState("missing")
+	c.Assert(err, Equals, goredis.ErrConnectionNotOpened{Name: "missing"})
+
+	err = goredis.OpenWithConfig(&goredis.Config{ConnectionName: "breaker", KeyPrefix: "test-breaker", BreakerThreshold: -1, BreakerProbes: -1})
+	c.Assert(err, ErrorMatches, "(?s).*breaker threshold must not be negative.*breaker probes must not be negative.*")
+}
+
+func stateOf(c *C) goredis.BreakerState {
+	state, err := goredis.CircuitBreakerState("breaker")
+	c.Assert(err, IsNil)
+	return state
+}
+
+// Fails every command while failing is true, as if the server timed out.
+type faultHook struct {
+	failing atomic.Bool
+	calls   atomic.Int64
+}
+
+func (h *faultHook) BeforeProcess(ctx context.Context, cmd redis.Cmder) (context.Context, error) {
+	h.calls.Add(1)
+	if h.failing.Load() {
+		return ctx, injectedFault{}
+	}
+	return ctx, nil
+}
+
+func (h *faultHook) AfterProcess(ctx context.Context, cmd redis.Cmder) error {
+	return nil
+}
+
+func (h *faultHook) BeforeProcessPipeline(ctx context.Context, cmds []redis.Cmder) (context.Context, error) {
+	return ctx, nil
+}
+
+func (h *faultHook) AfterProcessPipeline(ctx context.Context, cmds []redis.Cmder) error {
+	return nil
+}
+
+// The injected fault is a timeout of the client, as a [net.Error].
+type injectedFault struct{}
+
+func (injectedFault) Error() string   { return "injected fault" }
+func (injectedFault) Timeout() bool   { return true }
+func (injectedFault) Temporary() bool { return true }
@@ -2,6 +2,7 @@ package goredis
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
@@ -10,31 +11,30 @@ import (
 	"github.com/hecigo/goutils"
 )
 
-var caches map[string]*cache.Cache = make(map[string]*cache.Cache)
-var cacheConfigs map[string]*CacheConfig = make(map[string]*CacheConfig)
-
 type CacheConfig struct {
 	// Number of keys to cache in-process with TinyLFU algorith.
 	// Set by env `REDIS_CACHE_TINYFLU_SIZE`.
 	// Default: 10000
-	TinyFLUSize int
+	TinyFLUSize int `yaml:"tinyflu_size"`
 
 	// Cache duration of TinyFLU algorithm
 	// Set by env `REDIS_CACHE_TINYFLU_DURATION`.
 	// Default: 1m
-	TinyFLUDuration time.Duration
+	TinyFLUDuration time.Duration `yaml:"tinyflu_duration"`
 
 	// Default cache expiration time in Redis
 	// Set by env `REDIS_CACHE_TTL`.
 	// Default: 15m
-	DefaultTTL time.Duration
+	DefaultTTL time.Duration `yaml:"ttl"`
 }
 
 // Create a cache instance with the given connection name. The default connection name is `cache`.
 // We use TinyLFU as the default cache algorithm, [view more...]
 //
+// Returns the error if the connection can not be opened.
+//
 // [view more...]: https://redis.uptrace.dev/guide/go-redis-cache.html#go-redis-cache
-func EnableCache(name ...string) {
+func EnableCache(name ...string) error {
 	if len(name) == 0 {
 		name = append(name, "cache")
 	}
@@ -42,97 +42,238 @@ func EnableCache(name ...string) {
 	for _, connName := range name {
 
 		// open the Redis connection
-		Open(connName)
+		if err := Open(connName); err != nil {
+			return err
+		}
 
 		// use local in-process storage to cache the small subset of popular keys
 		// default cache 10,000 keys for 1 minute
-		tinyFLUSize := goutils.Env(fmt.Sprintf("REDIS%s_TINYFLU_SIZE", connName), 10000)
-		tinyFLUDuration := goutils.Env(fmt.Sprintf("REDIS%s_TINYFLU_DURATION", connName), time.Minute)
-		ttl := goutils.Env(fmt.Sprintf("REDIS%s_TTL", connName), 15*time.Minute)
-		cacheConfigs[connName] = &CacheConfig{
-			TinyFLUSize:     tinyFLUSize,
-			TinyFLUDuration: tinyFLUDuration,
-			DefaultTTL:      ttl,
+		cfg := &CacheConfig{}
+		cfg.setDefaults()
+		cfg.loadEnv(connName)
+		if err := enableCache(connName, cfg); err != nil {
+			return err
 		}
+	}
 
-		ctx := context.WithValue(context.Background(), goutils.CtxKey_ConnName, connName)
-		caches[connName] = cache.New(&cache.Options{
-			Redis:      Client(ctx),
-			LocalCache: cache.NewTinyLFU(tinyFLUSize, tinyFLUDuration),
-			Marshal:    json.Marshal,
-			Unmarshal:  json.Unmarshal,
-		})
+	return nil
+}
 
-		// print the cache information
-		goutils.Infof("───── RedisCache[%s]: enabled ─────\n", connName)
-		goutils.Infof("REDIS%s_TINYFLU_SIZE: %d\n", connName, tinyFLUSize)
-		goutils.Infof("REDIS%s_TINYFLU_DURATION: %s\n", connName, tinyFLUDuration)
-		goutils.Infof("REDIS%s_TTL: %s\n", connName, ttl)
-		goutils.Info("───────────────────────────────────\n")
+// Enable the cache of an opened connection from the given configuration, e.g. a connection opened by [OpenWithConfig],
+// which [EnableCache] would reopen from env. The zero-value fields are filled with the defaults, nil means all defaults.
+func EnableCacheWithConfig(connName string, config *CacheConfig) error {
+	var cfg CacheConfig
+	if config != nil {
+		cfg = *config
 	}
+	cfg.setDefaults()
+	return enableCache(connName, &cfg)
 }
 
-// Get the cache instance with the given connection name. If context is not provided, the default cache will be used.
-func Cache(ctx ...context.Context) *cache.Cache {
-	if len(caches) == 0 {
-		goutils.Panic("Redis cache is not enabled")
+// Create the cache on top of the opened connection with the same name, and register it.
+func enableCache(connName string, cfg *CacheConfig) error {
+	if err := cfg.validate(); err != nil {
+		return fmt.Errorf("redis cache[%s]: %w", connName, err)
 	}
 
-	if len(ctx) == 0 {
-		return caches["cache"]
+	ctx := context.WithValue(context.Background(), goutils.CtxKey_ConnName, connName)
+	if _, err := getConn(ctx); err != nil {
+		return err
 	}
 
-	connName := ctx[0].Value(goutils.CtxKey_ConnName)
-	if connName == nil || connName == "" {
-		return caches["cache"]
+	// built under the lock of the registry, a concurrent reopen can not leave it on a closed client
+	if !reg.addCache(connName, cfg, cache.NewTinyLFU(cfg.TinyFLUSize, cfg.TinyFLUDuration)) {
+		return ErrConnectionNotOpened{Name: connName}
 	}
 
-	if caches[connName.(string)] == nil {
-		goutils.Panicf("Redis cache `%s` is not enabled", connName)
-	}
+	// print the cache information
+	goutils.Infof("───── RedisCache[%s]: enabled ─────\n", connName)
+	goutils.Infof("REDIS%s_TINYFLU_SIZE: %d\n", connName, cfg.TinyFLUSize)
+	goutils.Infof("REDIS%s_TINYFLU_DURATION: %s\n", connName, cfg.TinyFLUDuration)
+	goutils.Infof("REDIS%s_TTL: %s\n", connName, cfg.DefaultTTL)
+	goutils.Info("───────────────────────────────────\n")
 
-	return caches[connName.(string)]
+	return nil
 }
 
-func getCacheConfig(ctx ...context.Context) *CacheConfig {
-	if len(cacheConfigs) == 0 {
-		goutils.Panic("Redis cache is not enabled")
+// Disable the cache with the given connection name, the default connection name is `cache`.
+// The connection is kept opened, then [GetCache] and [SetCache] return [ErrCacheNotEnabled] until [EnableCache] again.
+// Nothing happens if the cache is not enabled.
+func DisableCache(name ...string) {
+	if len(name) == 0 {
+		name = append(name, "cache")
+	}
+	for _, connName := range name {
+		reg.removeCache(connName)
 	}
+}
 
-	if len(ctx) == 0 {
-		return cacheConfigs["cache"]
+// Create the cache on top of the clients of the connection. The local cache is shared by the master and the replicas,
+// and it is kept when the connection is reopened, so that the reads still hit the popular keys in-process.
+func newCacheEntry(conn *connection, cfg *CacheConfig, local cache.LocalCache) *cacheEntry {
+	entry := &cacheEntry{
+		cache: cache.New(&cache.Options{
+			Redis:      conn.client,
+			LocalCache: local,
+			Marshal:    json.Marshal,
+			Unmarshal:  json.Unmarshal,
+		}),
+		local:      local,
+		readOnly:   conn.config.ReadOnly,
+		connConfig: conn.config,
+		config:     cfg,
+	}
+	if conn.replica != nil {
+		entry.replicaCache = cache.New(&cache.Options{
+			Redis:      conn.replica,
+			LocalCache: local,
+			Marshal:    json.Marshal,
+			Unmarshal:  json.Unmarshal,
+		})
 	}
+	return entry
+}
 
-	connName := ctx[0].Value(goutils.CtxKey_ConnName)
-	if connName == nil || connName == "" {
-		return cacheConfigs["cache"]
+// Fill the zero-value fields with the defaults.
+func (cfg *CacheConfig) setDefaults() {
+	if cfg.TinyFLUSize == 0 {
+		cfg.TinyFLUSize = 10000
 	}
+	if cfg.TinyFLUDuration == 0 {
+		cfg.TinyFLUDuration = time.Minute
+	}
+	if cfg.DefaultTTL == 0 {
+		cfg.DefaultTTL = 15 * time.Minute
+	}
+}
 
-	if cacheConfigs[connName.(string)] == nil {
-		goutils.Panicf("Redis cache `%s` is not enabled", connName)
+// Override the configuration by env `REDIS<name>_*`, the fields of the unset env keep their values.
+func (cfg *CacheConfig) loadEnv(connName string) {
+	cfg.TinyFLUSize = goutils.Env(fmt.Sprintf("REDIS%s_TINYFLU_SIZE", connName), cfg.TinyFLUSize)
+	cfg.TinyFLUDuration = envDuration(fmt.Sprintf("REDIS%s_TINYFLU_DURATION", connName), cfg.TinyFLUDuration)
+	cfg.DefaultTTL = envDuration(fmt.Sprintf("REDIS%s_TTL", connName), cfg.DefaultTTL)
+}
+
+// Validate the configuration before enabling the cache.
+func (cfg *CacheConfig) validate() error {
+	var errs []error
+	if cfg.TinyFLUSize < 0 {
+		errs = append(errs, errors.New("tinyflu size must not be negative"))
+	}
+	if cfg.TinyFLUDuration < 0 {
+		errs = append(errs, errors.New("tinyflu duration must not be negative"))
+	}
+	if cfg.DefaultTTL < 0 {
+		errs = append(errs, errors.New("ttl must not be negative"))
 	}
+	return errors.Join(errs...)
+}
+
+// Get the cache instance with the given connection name. If context is not provided, the default cache will be used.
+// It panics if the cache is not enabled, use [CacheE] to handle the error instead.
+func Cache(ctx ...context.Context) *cache.Cache {
+	c, err := CacheE(ctx...)
+	if err != nil {
+		goutils.Panic(err)
+	}
+	return c
+}
 
-	return cacheConfigs[connName.(string)]
+// Similar to [Cache], but returns [ErrCacheNotEnabled] if the cache is not enabled.
+func CacheE(ctx ...context.Context) (*cache.Cache, error) {
+	entry, err := getCacheEntry(ctx...)
+	if err != nil {
+		return nil, err
+	}
+	return entry.cache, nil
 }
 
 // Get the value from the cache. The value must be a pointer.
+// It is read from replicas if the connection has any, following [CtxKey_ReadPreference] in context.
+//
+// The keys of the cache are not prefixed, unless the call sets [WithKeyPrefix] or [WithKeySegments].
+// The segments are appended to [Config].KeyPrefix if the call does not set a prefix.
+//
+// If the connection is unavailable, the value is read again from the cache of [Config].FallbackConnection,
+// which must be enabled by [EnableCache]. The value is compared with the cache of [Config].ShadowConnection
+// if [Config].ShadowCompareReads is enabled.
 func GetCache(ctx context.Context, key string, value interface{}) error {
-	return Cache(ctx).Get(ctx, key, value)
+	err := withFailover(ctx, "cache", false, func(ctx context.Context) error {
+		return getCache(ctx, key, value)
+	})
+	if err == nil {
+		if s := shadowOf(ctx, "cache"); s != nil {
+			s.compareCache(ctx, key, value)
+		}
+	}
+	return err
+}
+
+// Get the value from the cache of the connection of ctx, see [GetCache].
+func getCache(ctx context.Context, key string, value interface{}) error {
+	entry, err := getCacheEntry(ctx)
+	if err != nil {
+		return err
+	}
+	key, err = entry.key(ctx, key)
+	if err != nil {
+		return err
+	}
+
+	ctx, span := startSpan(ctx, "Cache.Get", key)
+	defer span.End()
+
+	return entry.reader(ctx).Get(ctx, key, value)
 }
 
 // Set the value to the cache. If TTL is not provided, [DefaultTTL] will be used from env.
+// The key is prefixed as [GetCache]. It fails over only if [Config].FailoverWrites is enabled,
+// and it is mirrored to the cache of [Config].ShadowConnection.
 func SetCache(ctx context.Context, key string, value interface{}, TTL ...time.Duration) error {
+	return withShadow(ctx, "cache", "SetCache "+key, func(ctx context.Context) error {
+		return setCache(ctx, key, value, TTL...)
+	})
+}
+
+// Set the value to the cache of the connection of ctx, see [SetCache].
+func setCache(ctx context.Context, key string, value interface{}, TTL ...time.Duration) error {
+	entry, err := getCacheEntry(ctx)
+	if err != nil {
+		return err
+	}
+	key, err = entry.key(ctx, key)
+	if err != nil {
+		return err
+	}
+
+	ctx, span := startSpan(ctx, "Cache.Set", key)
+	defer span.End()
+
 	var ttl time.Duration
 	if len(TTL) == 0 {
-		ttl = getCacheConfig(ctx).DefaultTTL
+		ttl = entry.config.DefaultTTL
 	} else {
 		ttl = TTL[0]
 	}
 
-	return Cache(ctx).Set(&cache.Item{
+	return entry.cache.Set(&cache.Item{
 		Ctx:   ctx,
 		Key:   key,
 		Value: value,
 		TTL:   ttl,
 	})
 }
+
+// Returns the key of the cache for the call. It is prefixed only if the call scopes the keys,
+// so that the keys written before the scopes existed are still found.
+func (entry *cacheEntry) key(ctx context.Context, key string) (string, error) {
+	_, scoped, err := ctxKeyScope(ctx, entry.connConfig.KeyPrefix, entry.connConfig.KeySeparator)
+	if err != nil || !scoped {
+		return key, err
+	}
+	kb, err := newKeyBuilder(ctx, entry.connConfig)
+	if err != nil {
+		return "", err
+	}
+	return kb.Key(key), nil
+}
@@ -0,0 +1,250 @@
+package goredis
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	json "github.com/goccy/go-json"
+	"gopkg.in/yaml.v3"
+)
+
+// The configuration file loaded by [LoadConfigFile]. The keys are the env suffixes in snake case,
+// e.g. `pool_size` for env `REDIS<name>_POOL_SIZE`, and the durations are strings such as `500ms`.
+//
+//	connections:
+//	  default:
+//	    addresses: [localhost:6379]
+//	    key_prefix: my-app
+//	  session:
+//	    addresses: ["redis://localhost:6379/1?pool_size=20"]
+//	    key_prefix: my-app-session
+//	    read_timeout: 500ms
+//	caches:
+//	  session:
+//	    tinyflu_size: 1000
+//	    ttl: 30m
+//
+// A cache is built on top of the connection with the same name, it is loaded from env if the file does not define it.
+type ConfigFile struct {
+	Connections map[string]*Config      `yaml:"connections"`
+	Caches      map[string]*CacheConfig `yaml:"caches"`
+}
+
+// Load the connections and the caches from a YAML or JSON file, then open all of them.
+// Env `REDIS<name>_*` overrides the values of the file, the default connection uses env `REDIS_*`.
+//
+// Every connection and cache is validated before any of them is opened, the problems are returned together
+// by [errors.Join]. The file is not opened partially if it is invalid.
+func LoadConfigFile(path string) error {
+	file, err := ReadConfigFile(path)
+	if err != nil {
+		return err
+	}
+
+	reg.setConfigFilePath(path)
+
+	var errs []error
+	for _, name := range file.connectionNames() {
+		if err := openWithConfig(file.Connections[name], fileSource(path, name), false); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	for _, name := range file.cacheNames() {
+		if _, ok := file.Connections[name]; !ok {
+			if err := Open(name); err != nil {
+				errs = append(errs, err)
+				continue
+			}
+		}
+		if err := enableCache(name, file.Caches[name]); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Read and validate the configuration file without opening the connections, see [LoadConfigFile].
+// The returned configurations are overridden by env and filled with the defaults.
+func ReadConfigFile(path string) (*ConfigFile, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("redis: %w", err)
+	}
+
+	file, err := parseConfigFile(b, filepath.Ext(path))
+	if err != nil {
+		return nil, fmt.Errorf("redis: %s: %w", path, err)
+	}
+
+	var errs []error
+	for _, name := range file.connectionNames() {
+		cfg := file.Connections[name]
+		if cfg == nil {
+			cfg = &Config{}
+			file.Connections[name] = cfg
+		}
+		cfg.ConnectionName = name
+		if err := cfg.normalize(); err != nil {
+			errs = append(errs, fmt.Errorf("redis[%s]: %w", name, err))
+			continue
+		}
+		if err := cfg.loadEnv(envName(name)); err != nil {
+			errs = append(errs, fmt.Errorf("redis[%s]: %w", name, err))
+			continue
+		}
+		cfg.setDefaults()
+		if err := cfg.validate(); err != nil {
+			errs = append(errs, fmt.Errorf("redis[%s]: %w", name, err))
+		}
+	}
+	for _, name := range file.cacheNames() {
+		cfg := file.Caches[name]
+		if cfg == nil {
+			cfg = &CacheConfig{}
+			file.Caches[name] = cfg
+		}
+		cfg.setDefaults()
+		cfg.loadEnv(envName(name))
+		if err := cfg.validate(); err != nil {
+			errs = append(errs, fmt.Errorf("redis cache[%s]: %w", name, err))
+		}
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return file, nil
+}
+
+// Parse the values as their env, the addresses can be URLs and the mode can be an alias.
+// The explicit settings win over the values of the URLs, e.g. `db: 5` over `redis://host/2`.
+// It is shared by [OpenWithConfig] and [LoadConfigFile], and does nothing on a parsed configuration.
+func (cfg *Config) normalize() error {
+	for _, addr := range cfg.Addresses {
+		if strings.Contains(addr, "://") {
+			explicit := *cfg
+			if err := cfg.parseURL(cfg.Addresses...); err != nil {
+				return err
+			}
+			cfg.keepExplicit(&explicit)
+			break
+		}
+	}
+	if cfg.Mode != Mode_Auto {
+		mode, err := ParseMode(string(cfg.Mode))
+		if err != nil {
+			return err
+		}
+		cfg.Mode = mode
+	}
+	tracing, err := ParseTracing(string(cfg.Tracing))
+	if err != nil {
+		return err
+	}
+	cfg.Tracing = tracing
+	return nil
+}
+
+// Restore the settings of explicit which are set, after the values of the URLs are applied by [Config.parseURL].
+func (cfg *Config) keepExplicit(explicit *Config) {
+	if len(explicit.BasicAuth) == 2 && (explicit.BasicAuth[0] != "" || explicit.BasicAuth[1] != "") {
+		cfg.BasicAuth = explicit.BasicAuth
+	}
+	if explicit.DB != 0 {
+		cfg.DB = explicit.DB
+	}
+	if explicit.DialTimeout != 0 {
+		cfg.DialTimeout = explicit.DialTimeout
+	}
+	if explicit.ReadTimeout != 0 {
+		cfg.ReadTimeout = explicit.ReadTimeout
+	}
+	if explicit.WriteTimeout != 0 {
+		cfg.WriteTimeout = explicit.WriteTimeout
+	}
+	if explicit.PoolSize != 0 {
+		cfg.PoolSize = explicit.PoolSize
+	}
+	if explicit.MaxRetries != 0 {
+		cfg.MaxRetries = explicit.MaxRetries
+	}
+	if explicit.MinRetryBackoff != 0 {
+		cfg.MinRetryBackoff = explicit.MinRetryBackoff
+	}
+	if explicit.MaxRetryBackoff != 0 {
+		cfg.MaxRetryBackoff = explicit.MaxRetryBackoff
+	}
+	if explicit.MinIdleConns != 0 {
+		cfg.MinIdleConns = explicit.MinIdleConns
+	}
+	if explicit.PoolTimeout != 0 {
+		cfg.PoolTimeout = explicit.PoolTimeout
+	}
+	if explicit.IdleTimeout != 0 {
+		cfg.IdleTimeout = explicit.IdleTimeout
+	}
+	if explicit.MaxConnAge != 0 {
+		cfg.MaxConnAge = explicit.MaxConnAge
+	}
+	if explicit.PoolFIFO {
+		cfg.PoolFIFO = true
+	}
+	if explicit.TLSEnabled {
+		cfg.TLSEnabled = true
+	}
+}
+
+// Decode the file by its extension, the unknown keys are rejected to catch typos.
+// JSON is converted to YAML first, so that both formats share the keys and the duration strings.
+func parseConfigFile(b []byte, ext string) (*ConfigFile, error) {
+	switch strings.ToLower(ext) {
+	case ".json":
+		var v interface{}
+		if err := json.Unmarshal(b, &v); err != nil {
+			return nil, err
+		}
+		yb, err := yaml.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		b = yb
+	case ".yaml", ".yml":
+	default:
+		return nil, fmt.Errorf("unsupported config file extension `%s`, expected .yaml, .yml or .json", ext)
+	}
+
+	file := &ConfigFile{}
+	dec := yaml.NewDecoder(bytes.NewReader(b))
+	dec.KnownFields(true)
+	if err := dec.Decode(file); err != nil {
+		return nil, err
+	}
+	if len(file.Connections) == 0 && len(file.Caches) == 0 {
+		return nil, errors.New("no connections or caches")
+	}
+	return file, nil
+}
+
+// Returns the names of the connections, sorted to open them in a stable order.
+func (file *ConfigFile) connectionNames() []string {
+	names := make([]string, 0, len(file.Connections))
+	for name := range file.Connections {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Returns the names of the caches, sorted to enable them in a stable order.
+func (file *ConfigFile) cacheNames() []string {
+	names := make([]string, 0, len(file.Caches))
+	for name := range file.Caches {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
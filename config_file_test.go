@@ -0,0 +1,126 @@
+package goredis_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/hecigo/goredis"
+	"github.com/hecigo/goutils"
+	. "gopkg.in/check.v1"
+)
+
+type ConfigFileSuite struct{}
+
+var _ = Suite(&ConfigFileSuite{})
+
+func (s *ConfigFileSuite) SetUpSuite(c *C) {
+	fmt.Println("SetUpSuite > ConfigFileSuite")
+	goutils.QuickLoad()
+}
+
+func (s *ConfigFileSuite) TearDownSuite(c *C) {
+	goredis.Close("file1", "file2")
+}
+
+// Test the connections and the caches are opened from a YAML file, env overrides the file
+func (s *ConfigFileSuite) TestYAML(c *C) {
+	path := writeConfigFile(c, "redis.yaml", `
+connections:
+  file1:
+    addresses: [localhost:6379]
+    key_prefix: test-file1
+    pool_size: 20
+    read_timeout: 500ms
+  file2:
+    addresses: ["redis://localhost:6379/1?pool_size=5"]
+    key_prefix: test-file2
+caches:
+  file2:
+    tinyflu_size: 100
+    ttl: 1m
+`)
+	os.Setenv("REDISFILE1_POOL_SIZE", "30")
+	defer os.Unsetenv("REDISFILE1_POOL_SIZE")
+	c.Assert(goredis.LoadConfigFile(path), IsNil)
+
+	cfg := goredis.GetConfig(context.WithValue(context.Background(), goutils.CtxKey_ConnName, "file1"))
+	c.Assert(cfg.PoolSize, Equals, 30)
+	c.Assert(cfg.ReadTimeout, Equals, 500*time.Millisecond)
+	c.Assert(cfg.KeyPrefix, Equals, "test-file1")
+
+	ctx := context.WithValue(context.Background(), goutils.CtxKey_ConnName, "file2")
+	cfg = goredis.GetConfig(ctx)
+	c.Assert(cfg.DB, Equals, 1)
+	c.Assert(cfg.PoolSize, Equals, 5)
+
+	desc := goredis.Describe()
+	c.Assert(desc.Caches["file2"], DeepEquals, goredis.CacheConfig{TinyFLUSize: 100, TinyFLUDuration: time.Minute, DefaultTTL: time.Minute})
+	c.Assert(goredis.SetCache(ctx, "test_file_cache", "value"), IsNil)
+}
+
+// Test a JSON file shares the keys of YAML
+func (s *ConfigFileSuite) TestJSON(c *C) {
+	path := writeConfigFile(c, "redis.json", `{
+	"connections": {
+		"file1": {"addresses": ["localhost:6379"], "key_prefix": "test-file1", "mode": "standalone", "dial_timeout": "2s"}
+	}
+}`)
+	file, err := goredis.ReadConfigFile(path)
+	c.Assert(err, IsNil)
+	c.Assert(file.Connections["file1"].Mode, Equals, goredis.Mode_Standalone)
+	c.Assert(file.Connections["file1"].DialTimeout, Equals, 2*time.Second)
+}
+
+// Test the explicit settings of the file win over the values of the URLs
+func (s *ConfigFileSuite) TestURLAndExplicit(c *C) {
+	path := writeConfigFile(c, "redis.yaml", `
+connections:
+  file1:
+    addresses: ["redis://localhost:6379/2?pool_size=5&dial_timeout=3s"]
+    db: 5
+    dial_timeout: 2s
+`)
+	file, err := goredis.ReadConfigFile(path)
+	c.Assert(err, IsNil)
+	cfg := file.Connections["file1"]
+	c.Assert(cfg.Addresses, DeepEquals, []string{"localhost:6379"})
+	c.Assert(cfg.DB, Equals, 5)
+	c.Assert(cfg.DialTimeout, Equals, 2*time.Second)
+	c.Assert(cfg.PoolSize, Equals, 5)
+}
+
+// Test every problem of the file is returned at once
+func (s *ConfigFileSuite) TestValidation(c *C) {
+	path := writeConfigFile(c, "redis.yml", `
+connections:
+  file1:
+    pool_size: -1
+    mode: failover
+  file2:
+    min_idle_conns: -1
+caches:
+  file2:
+    ttl: -1m
+`)
+	_, err := goredis.ReadConfigFile(path)
+	c.Assert(err, NotNil)
+	c.Assert(err, ErrorMatches, `(?s).*redis\[file1\]: .*pool size must not be negative.*`)
+	c.Assert(err, ErrorMatches, "(?s).*mode `failover` requires master name.*")
+	c.Assert(err, ErrorMatches, `(?s).*redis\[file2\]: min idle conns must not be negative.*`)
+	c.Assert(err, ErrorMatches, `(?s).*redis cache\[file2\]: ttl must not be negative.*`)
+
+	path = writeConfigFile(c, "redis.yaml", "connections:\n  file1:\n    pool_sise: 10\n")
+	c.Assert(goredis.LoadConfigFile(path), ErrorMatches, "(?s).*field pool_sise not found.*")
+
+	path = writeConfigFile(c, "redis.toml", "")
+	c.Assert(goredis.LoadConfigFile(path), ErrorMatches, ".*unsupported config file extension.*")
+}
+
+func writeConfigFile(c *C, name string, content string) string {
+	path := filepath.Join(c.MkDir(), name)
+	c.Assert(os.WriteFile(path, []byte(content), 0600), IsNil)
+	return path
+}
@@ -0,0 +1,164 @@
+package goredis
+
+import (
+	"context"
+	"fmt"
+	"math"
+
+	"github.com/hecigo/goutils"
+)
+
+const (
+	CtxKey_KeyPrefix   ctxKeyType_Redis = "redis_key_prefix"   // the key prefix of a call, set by [WithKeyPrefix]
+	CtxKey_KeySegments ctxKeyType_Redis = "redis_key_segments" // the segments appended to the key prefix, set by [WithKeySegments]
+)
+
+// Returns a copy of ctx to run the call on the connection with name, see [goutils.CtxKey_ConnName].
+func WithConnection(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, goutils.CtxKey_ConnName, name)
+}
+
+// Returns a copy of ctx to read or write the Redis data type, one of [HASH], [LIST], [SET] or [ZSET].
+// See [CtxKey_DataType].
+func WithDataType(ctx context.Context, dataType string) context.Context {
+	return context.WithValue(ctx, CtxKey_DataType, dataType)
+}
+
+// Returns a copy of ctx to get the range [start, stop] of a [LIST] or a [ZSET].
+// See [CtxKey_SliceStart] and [CtxKey_SliceStop].
+func WithRange(ctx context.Context, start int64, stop int64) context.Context {
+	ctx = context.WithValue(ctx, CtxKey_SliceStart, start)
+	return context.WithValue(ctx, CtxKey_SliceStop, stop)
+}
+
+// Returns a copy of ctx to order a [ZSET] by descending (true) or ascending (false) of score.
+// See [CtxKey_SliceReverse].
+func WithReverse(ctx context.Context, rev bool) context.Context {
+	return context.WithValue(ctx, CtxKey_SliceReverse, rev)
+}
+
+// Returns a copy of ctx to prefix the keys of the call with prefix instead of [Config].KeyPrefix.
+// See [CtxKey_KeyPrefix].
+func WithKeyPrefix(ctx context.Context, prefix string) context.Context {
+	return context.WithValue(ctx, CtxKey_KeyPrefix, prefix)
+}
+
+// Returns a copy of ctx to append segments to the key prefix of the call, e.g. a tenant id,
+// so that many tenants share one connection without sharing keys:
+//
+//	ctx = goredis.WithKeySegments(ctx, "tenant-1")
+//	goredis.Set(ctx, "profile", v) // key `<prefix>.tenant-1.profile`
+//
+// The segments are appended to the prefix set by [WithKeyPrefix], or to [Config].KeyPrefix,
+// joined by [Config].KeySeparator.
+// Calling it again appends more segments after the existing ones. See [CtxKey_KeySegments].
+func WithKeySegments(ctx context.Context, segments ...string) context.Context {
+	prev, _ := ctx.Value(CtxKey_KeySegments).([]string)
+	all := make([]string, 0, len(prev)+len(segments))
+	all = append(append(all, prev...), segments...)
+	return context.WithValue(ctx, CtxKey_KeySegments, all)
+}
+
+// Get the data type from [CtxKey_DataType] in context, or an empty string if it is not set.
+func ctxDataType(ctx context.Context) (string, error) {
+	switch v := ctx.Value(CtxKey_DataType).(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	default:
+		return "", fmt.Errorf("redis: %s must be a string, got %T", CtxKey_DataType, v)
+	}
+}
+
+// Get the range from [CtxKey_SliceStart] and [CtxKey_SliceStop] in context, it is the whole slice if they are not set.
+func ctxRange(ctx context.Context) (start int64, stop int64, err error) {
+	start, err = ctxInt64(ctx, CtxKey_SliceStart, 0)
+	if err != nil {
+		return 0, 0, err
+	}
+	stop, err = ctxInt64(ctx, CtxKey_SliceStop, -1)
+	if err != nil {
+		return 0, 0, err
+	}
+	return start, stop, nil
+}
+
+// Get the order from [CtxKey_SliceReverse] in context, it is descending if it is not set.
+func ctxReverse(ctx context.Context) (bool, error) {
+	switch v := ctx.Value(CtxKey_SliceReverse).(type) {
+	case nil:
+		return true, nil
+	case bool:
+		return v, nil
+	default:
+		return false, fmt.Errorf("redis: %s must be a bool, got %T", CtxKey_SliceReverse, v)
+	}
+}
+
+// Get the key prefix of the call: [CtxKey_KeyPrefix] in context or the prefix of the connection,
+// followed by the segments of [CtxKey_KeySegments] joined by sep. scoped is true if the context sets any of them.
+func ctxKeyScope(ctx context.Context, connPrefix string, sep string) (prefix string, scoped bool, err error) {
+	prefix = connPrefix
+	switch v := ctx.Value(CtxKey_KeyPrefix).(type) {
+	case nil:
+	case string:
+		if v == "" {
+			return "", false, fmt.Errorf("redis: %s must not be empty", CtxKey_KeyPrefix)
+		}
+		prefix, scoped = v, true
+	default:
+		return "", false, fmt.Errorf("redis: %s must be a string, got %T", CtxKey_KeyPrefix, v)
+	}
+
+	switch v := ctx.Value(CtxKey_KeySegments).(type) {
+	case nil:
+	case []string:
+		for _, seg := range v {
+			if seg == "" {
+				return "", false, fmt.Errorf("redis: %s must not contain an empty segment", CtxKey_KeySegments)
+			}
+			prefix += sep + seg
+			scoped = true
+		}
+	default:
+		return "", false, fmt.Errorf("redis: %s must be a []string, got %T", CtxKey_KeySegments, v)
+	}
+	return prefix, scoped, nil
+}
+
+// Get an integer from context, any kind of int is accepted. Returns def if it is not set.
+func ctxInt64(ctx context.Context, key ctxKeyType_Redis, def int64) (int64, error) {
+	switch v := ctx.Value(key).(type) {
+	case nil:
+		return def, nil
+	case int64:
+		return v, nil
+	case int:
+		return int64(v), nil
+	case int32:
+		return int64(v), nil
+	case int16:
+		return int64(v), nil
+	case int8:
+		return int64(v), nil
+	case uint:
+		if uint64(v) > math.MaxInt64 {
+			return 0, fmt.Errorf("redis: %s overflows int64: %d", key, v)
+		}
+		return int64(v), nil
+	case uint64:
+		if v > math.MaxInt64 {
+			return 0, fmt.Errorf("redis: %s overflows int64: %d", key, v)
+		}
+		return int64(v), nil
+	case uint32:
+		return int64(v), nil
+	case uint16:
+		return int64(v), nil
+	case uint8:
+		return int64(v), nil
+	default:
+		return 0, fmt.Errorf("redis: %s must be an integer, got %T", key, v)
+	}
+}
@@ -0,0 +1,137 @@
+package goredis_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/hecigo/goredis"
+	"github.com/hecigo/goutils"
+	. "gopkg.in/check.v1"
+)
+
+type ContextSuite struct{}
+
+var _ = Suite(&ContextSuite{})
+
+func (s *ContextSuite) SetUpSuite(c *C) {
+	fmt.Println("SetUpSuite > ContextSuite")
+	goutils.QuickLoad()
+	os.Setenv("REDISCTX_KEY_PREFIX", "test-ctx")
+	c.Assert(goredis.Open("ctx"), IsNil)
+}
+
+func (s *ContextSuite) TearDownSuite(c *C) {
+	goredis.Close("ctx")
+	os.Unsetenv("REDISCTX_KEY_PREFIX")
+}
+
+// Test the helpers set the values read by the handler
+func (s *ContextSuite) TestHelpers(c *C) {
+	ctx := goredis.WithDataType(goredis.WithConnection(context.Background(), "ctx"), goredis.LIST)
+	c.Assert(goredis.Set(ctx, "test_ctx_list", []int{1, 2, 3, 4}), IsNil)
+
+	l, err := goredis.Get[[]int](goredis.WithRange(ctx, 1, 2), "test_ctx_list")
+	c.Assert(err, IsNil)
+	c.Assert(l, DeepEquals, []int{2, 3})
+
+	ctx = goredis.WithDataType(goredis.WithConnection(context.Background(), "ctx"), goredis.ZSET)
+	c.Assert(goredis.GetRankingBoard(ctx, "test_ctx_zset").UpsertMulti(map[string]float64{"a": 1, "b": 2, "c": 3}), IsNil)
+
+	z, err := goredis.Get[[]string](goredis.WithReverse(goredis.WithRange(ctx, 0, 1), false), "test_ctx_zset")
+	c.Assert(err, IsNil)
+	c.Assert(z, DeepEquals, []string{"a", "b"})
+}
+
+// Test the key prefix of the call replaces the prefix of the connection
+func (s *ContextSuite) TestKeyPrefix(c *C) {
+	ctx := goredis.WithConnection(context.Background(), "ctx")
+	tenant := goredis.WithKeyPrefix(ctx, "test-ctx-tenant")
+	c.Assert(goredis.Set(tenant, "test_ctx_key", "tenant"), IsNil)
+
+	v, err := goredis.Client(ctx).Get(ctx, "test-ctx-tenant.test_ctx_key").Result()
+	c.Assert(err, IsNil)
+	c.Assert(v, Equals, "tenant")
+
+	m, err := goredis.Get[string](tenant, "test_ctx_key", "test_ctx_missing")
+	c.Assert(err, IsNil)
+	c.Assert(*m.(map[string]*string)["test_ctx_key"], Equals, "tenant")
+
+	_, err = goredis.Get[string](goredis.WithKeyPrefix(ctx, ""), "test_ctx_key")
+	c.Assert(err, ErrorMatches, ".*redis_key_prefix must not be empty.*")
+}
+
+// Test the values of wrong types are returned as errors instead of panics
+func (s *ContextSuite) TestWrongTypes(c *C) {
+	ctx := goredis.WithDataType(goredis.WithConnection(context.Background(), "ctx"), goredis.LIST)
+	c.Assert(goredis.Set(ctx, "test_ctx_list", []int{1, 2, 3, 4}), IsNil)
+
+	// any kind of int is accepted
+	l, err := goredis.Get[[]int](context.WithValue(ctx, goredis.CtxKey_SliceStart, 2), "test_ctx_list")
+	c.Assert(err, IsNil)
+	c.Assert(l, DeepEquals, []int{3, 4})
+
+	_, err = goredis.Get[[]int](context.WithValue(ctx, goredis.CtxKey_SliceStop, "1"), "test_ctx_list")
+	c.Assert(err, ErrorMatches, "redis: redis_slice_stop must be an integer, got string")
+
+	_, err = goredis.Get[[]int](context.WithValue(ctx, goredis.CtxKey_DataType, 1), "test_ctx_list")
+	c.Assert(err, ErrorMatches, "redis: redis_data_type must be a string, got int")
+
+	zset := goredis.WithDataType(ctx, goredis.ZSET)
+	_, err = goredis.Get[[]string](context.WithValue(zset, goredis.CtxKey_SliceReverse, "false"), "test_ctx_zset")
+	c.Assert(err, ErrorMatches, "redis: redis_slice_rev must be a bool, got string")
+
+	c.Assert(goredis.Set(context.WithValue(ctx, goredis.CtxKey_KeyPrefix, 1), "test_ctx_key", "v"), ErrorMatches, ".*must be a string.*")
+
+	// a wrongly typed connection name does not fall back to the default connection
+	named := context.WithValue(ctx, goutils.CtxKey_ConnName, 1)
+	c.Assert(goredis.Set(named, "test_ctx_key", "v"), ErrorMatches, "redis: conn_name must be a string, got int")
+	_, err = goredis.ClientE(named)
+	c.Assert(err, ErrorMatches, "redis: conn_name must be a string, got int")
+	_, err = goredis.CacheE(named)
+	c.Assert(err, ErrorMatches, "redis: conn_name must be a string, got int")
+}
+
+// Test the segments scope the keys of every helper, and the results are keyed without them
+func (s *ContextSuite) TestKeySegments(c *C) {
+	ctx := goredis.WithConnection(context.Background(), "ctx")
+	tenant := goredis.WithKeySegments(ctx, "tenant-1")
+	c.Assert(goredis.MSet(tenant, map[string]interface{}{"test_ctx_k1": "v1", "test_ctx_k2": "v2"}), IsNil)
+
+	v, err := goredis.Client(ctx).Get(ctx, "test-ctx.tenant-1.test_ctx_k1").Result()
+	c.Assert(err, IsNil)
+	c.Assert(v, Equals, "v1")
+
+	keys := []string{"test_ctx_k1", "test_ctx_k2"}
+	m, err := goredis.Get[string](tenant, keys...)
+	c.Assert(err, IsNil)
+	c.Assert(*m.(map[string]*string)["test_ctx_k2"], Equals, "v2")
+
+	// another tenant does not see the keys
+	other, err := goredis.Get[string](goredis.WithKeySegments(ctx, "tenant-2"), "test_ctx_k1")
+	c.Assert(err, IsNil)
+	c.Assert(other, IsNil)
+
+	// the segments are appended to the prefix of the call
+	nested := goredis.WithKeySegments(goredis.WithKeyPrefix(tenant, "test-ctx-app"), "eu")
+	c.Assert(goredis.GetRankingBoard(nested, "test_ctx_zset").Id, Equals, "test-ctx-app.tenant-1.eu.test_ctx_zset")
+
+	c.Assert(goredis.GetRankingBoard(goredis.WithKeySegments(ctx, ""), "test_ctx_zset").Delete(), ErrorMatches, ".*empty segment.*")
+}
+
+// Test the keys of the cache are prefixed only if the call scopes them
+func (s *ContextSuite) TestCacheKeySegments(c *C) {
+	c.Assert(goredis.EnableCache("ctx"), IsNil)
+	ctx := goredis.WithConnection(context.Background(), "ctx")
+	tenant := goredis.WithKeySegments(ctx, "tenant-1")
+
+	c.Assert(goredis.SetCache(ctx, "test_ctx_cache", "shared"), IsNil)
+	c.Assert(goredis.SetCache(tenant, "test_ctx_cache", "tenant"), IsNil)
+	c.Assert(goredis.Client(ctx).Exists(ctx, "test-ctx.tenant-1.test_ctx_cache").Val(), Equals, int64(1))
+
+	var v string
+	c.Assert(goredis.GetCache(ctx, "test_ctx_cache", &v), IsNil)
+	c.Assert(v, Equals, "shared")
+	c.Assert(goredis.GetCache(tenant, "test_ctx_cache", &v), IsNil)
+	c.Assert(v, Equals, "tenant")
+}
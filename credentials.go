@@ -0,0 +1,158 @@
+package goredis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/hecigo/goutils"
+)
+
+// Provides the credentials to authenticate every new connection of the pool, so that rotated passwords
+// are picked up without reopening the connection. Set it by [Config].CredentialsProvider.
+type CredentialsProvider interface {
+	// Returns the current username and password. The username is empty to authenticate as the default user,
+	// both are empty to skip the authentication.
+	Credentials(ctx context.Context) (username string, password string, err error)
+
+	// Reload the credentials after an authentication failure, e.g. the password has been rotated on the server
+	// but the cached one is outdated.
+	Refresh(ctx context.Context) error
+}
+
+// The [CredentialsProvider] of a callback, such as a secret manager client.
+// The callback is called on every new connection, so [CredentialsFunc.Refresh] does nothing.
+type CredentialsFunc func(ctx context.Context) (username string, password string, err error)
+
+// Implements [CredentialsProvider].
+func (f CredentialsFunc) Credentials(ctx context.Context) (string, string, error) {
+	return f(ctx)
+}
+
+// Implements [CredentialsProvider].
+func (f CredentialsFunc) Refresh(ctx context.Context) error {
+	return nil
+}
+
+// Returns the [CredentialsProvider] of a file, which is rewritten by a sidecar when the secrets are rotated.
+// The file contains either `password` or `username:password`, like env `REDIS<name>_BASIC_AUTH`.
+// The file is read again when it is modified, or after an authentication failure.
+func FileCredentials(path string) CredentialsProvider {
+	return &fileCredentials{path: path}
+}
+
+type fileCredentials struct {
+	path string
+
+	mu       sync.Mutex
+	loaded   bool
+	modTime  time.Time
+	size     int64
+	username string
+	password string
+}
+
+// Implements [CredentialsProvider].
+func (f *fileCredentials) Credentials(ctx context.Context) (string, string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	info, err := os.Stat(f.path)
+	if err != nil {
+		return "", "", fmt.Errorf("credentials file: %w", err)
+	}
+	if f.loaded && info.ModTime().Equal(f.modTime) && info.Size() == f.size {
+		return f.username, f.password, nil
+	}
+
+	b, err := os.ReadFile(f.path)
+	if err != nil {
+		return "", "", fmt.Errorf("credentials file: %w", err)
+	}
+	f.username, f.password = parseCredentials(string(b))
+	f.modTime, f.size, f.loaded = info.ModTime(), info.Size(), true
+	return f.username, f.password, nil
+}
+
+// Implements [CredentialsProvider].
+func (f *fileCredentials) Refresh(ctx context.Context) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.loaded = false
+	return nil
+}
+
+// Parse `password` or `username:password`, the surrounding spaces and line breaks are trimmed.
+func parseCredentials(s string) (string, string) {
+	s = strings.TrimSpace(s)
+	if username, password, ok := strings.Cut(s, ":"); ok {
+		return username, password
+	}
+	return "", s
+}
+
+// Returns the hook to initialize every new connection, authenticate by [Config].CredentialsProvider
+// then select the DB. The DB is 0 if the client selects it itself.
+func (cfg *Config) onConnect(db int) func(ctx context.Context, cn *redis.Conn) error {
+	if cfg.CredentialsProvider == nil {
+		return selectDB(db, nil)
+	}
+
+	provider := cfg.CredentialsProvider
+	connName := cfg.ConnectionName
+	next := selectDB(db, nil)
+	return func(ctx context.Context, cn *redis.Conn) error {
+		err := authenticate(ctx, cn, provider)
+		if isAuthError(err) {
+			// the credentials may have been rotated, reload them and try again
+			goutils.Warnf("redis[%s]: authentication failed, refreshing credentials: %s", connName, err)
+			if rerr := provider.Refresh(ctx); rerr != nil {
+				return fmt.Errorf("refresh credentials: %w", rerr)
+			}
+			err = authenticate(ctx, cn, provider)
+		}
+		if err != nil {
+			return err
+		}
+		if next != nil {
+			return next(ctx, cn)
+		}
+		return nil
+	}
+}
+
+// Authenticate the connection with the current credentials of the provider.
+func authenticate(ctx context.Context, cn *redis.Conn, provider CredentialsProvider) error {
+	username, password, err := provider.Credentials(ctx)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case username != "":
+		return cn.AuthACL(ctx, username, password).Err()
+	case password != "":
+		return cn.Auth(ctx, password).Err()
+	default:
+		return nil
+	}
+}
+
+// Returns true if the server rejects the credentials.
+func isAuthError(err error) bool {
+	var redisErr redis.Error
+	if err == nil || !errors.As(err, &redisErr) {
+		return false
+	}
+
+	msg := redisErr.Error()
+	return strings.HasPrefix(msg, "WRONGPASS") ||
+		strings.HasPrefix(msg, "NOAUTH") ||
+		strings.Contains(msg, "invalid password") ||
+		strings.Contains(msg, "invalid username-password")
+}
@@ -0,0 +1,113 @@
+package goredis_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+
+	"github.com/hecigo/goredis"
+	"github.com/hecigo/goutils"
+	. "gopkg.in/check.v1"
+)
+
+// Requires a local Redis at localhost:6379 without password, the provider skips AUTH for empty credentials.
+type CredentialsSuite struct{}
+
+var _ = Suite(&CredentialsSuite{})
+
+func (s *CredentialsSuite) SetUpSuite(c *C) {
+	fmt.Println("SetUpSuite > CredentialsSuite")
+	goutils.QuickLoad()
+}
+
+func (s *CredentialsSuite) TearDownTest(c *C) {
+	goredis.Close("credentials")
+}
+
+// Test the file is read again after it is rewritten or refreshed
+func (s *CredentialsSuite) TestFileCredentials(c *C) {
+	ctx := context.Background()
+	path := filepath.Join(c.MkDir(), "redis-credentials")
+	c.Assert(os.WriteFile(path, []byte("secret\n"), 0600), IsNil)
+
+	provider := goredis.FileCredentials(path)
+	username, password, err := provider.Credentials(ctx)
+	c.Assert(err, IsNil)
+	c.Assert(username, Equals, "")
+	c.Assert(password, Equals, "secret")
+
+	// rotated by a sidecar
+	c.Assert(os.WriteFile(path, []byte("app:rotated:secret"), 0600), IsNil)
+	future := time.Now().Add(time.Minute)
+	c.Assert(os.Chtimes(path, future, future), IsNil)
+	username, password, err = provider.Credentials(ctx)
+	c.Assert(err, IsNil)
+	c.Assert(username, Equals, "app")
+	c.Assert(password, Equals, "rotated:secret")
+
+	c.Assert(os.Remove(path), IsNil)
+	c.Assert(provider.Refresh(ctx), IsNil)
+	_, _, err = provider.Credentials(ctx)
+	c.Assert(err, ErrorMatches, "credentials file: .*")
+}
+
+// Test an authentication failure refreshes the credentials before giving up
+func (s *CredentialsSuite) TestRefreshOnAuthFailure(c *C) {
+	var rotated, calls int32
+	provider := &rotatingProvider{
+		fn: goredis.CredentialsFunc(func(ctx context.Context) (string, string, error) {
+			atomic.AddInt32(&calls, 1)
+			if atomic.LoadInt32(&rotated) == 0 {
+				return "goredis-outdated", "outdated", nil
+			}
+			return "", "", nil
+		}),
+		refresh: func() { atomic.StoreInt32(&rotated, 1) },
+	}
+
+	c.Assert(goredis.OpenWithConfig(&goredis.Config{
+		ConnectionName:      "credentials",
+		KeyPrefix:           "test-credentials",
+		CredentialsProvider: provider,
+	}), IsNil)
+
+	ctx := context.WithValue(context.Background(), goutils.CtxKey_ConnName, "credentials")
+	_, err := goredis.Client(ctx).Ping(ctx).Result()
+	c.Assert(err, IsNil)
+	c.Assert(atomic.LoadInt32(&rotated), Equals, int32(1))
+	c.Assert(atomic.LoadInt32(&calls), Equals, int32(2))
+}
+
+// Test the error of the server is returned if the refreshed credentials are still rejected
+func (s *CredentialsSuite) TestAuthFailure(c *C) {
+	c.Assert(goredis.OpenWithConfig(&goredis.Config{
+		ConnectionName: "credentials",
+		KeyPrefix:      "test-credentials",
+		MaxRetries:     -1,
+		CredentialsProvider: goredis.CredentialsFunc(func(ctx context.Context) (string, string, error) {
+			return "goredis-outdated", "outdated", nil
+		}),
+	}), IsNil)
+
+	ctx := context.WithValue(context.Background(), goutils.CtxKey_ConnName, "credentials")
+	_, err := goredis.Client(ctx).Ping(ctx).Result()
+	c.Assert(err, ErrorMatches, "WRONGPASS.*")
+}
+
+// A provider which calls refresh on [goredis.CredentialsProvider.Refresh].
+type rotatingProvider struct {
+	fn      goredis.CredentialsFunc
+	refresh func()
+}
+
+func (p *rotatingProvider) Credentials(ctx context.Context) (string, string, error) {
+	return p.fn(ctx)
+}
+
+func (p *rotatingProvider) Refresh(ctx context.Context) error {
+	p.refresh()
+	return nil
+}
@@ -0,0 +1,41 @@
+package goredis
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Returned after [CloseAll], until a connection is opened again.
+var ErrShutdown = errors.New("redis: connections are shut down")
+
+// Returned when the connection with name is not opened by [Open] or [OpenWithConfig].
+type ErrConnectionNotOpened struct {
+	Name string
+}
+
+func (e ErrConnectionNotOpened) Error() string {
+	return fmt.Sprintf("redis: connection `%s` is not opened", e.Name)
+}
+
+// Returned when the cache with name is not enabled by [EnableCache].
+type ErrCacheNotEnabled struct {
+	Name string
+}
+
+func (e ErrCacheNotEnabled) Error() string {
+	return fmt.Sprintf("redis: cache `%s` is not enabled", e.Name)
+}
+
+// Returned while the circuit breaker of the connection with name is open, see [Config].BreakerThreshold.
+// The commands fail fast without reaching the server.
+type ErrCircuitOpen struct {
+	Name string
+
+	// The time until the breaker lets probe commands through, 0 if it is already probing.
+	RetryAfter time.Duration
+}
+
+func (e ErrCircuitOpen) Error() string {
+	return fmt.Sprintf("redis: circuit breaker of connection `%s` is open", e.Name)
+}
@@ -0,0 +1,64 @@
+package goredis
+
+import (
+	"context"
+	"errors"
+
+	"github.com/hecigo/goutils"
+)
+
+// The context key of the connection which failed over, so that a call fails over only once.
+type ctxKeyType_Failover struct{}
+
+// Run fn on the connection of ctx, then again on [Config].FallbackConnection if the connection is unavailable.
+// A write fails over only if [Config].FailoverWrites is enabled.
+// defaultName is the connection if ctx does not name one, e.g. `default` or `cache`.
+func withFailover(ctx context.Context, defaultName string, write bool, fn func(ctx context.Context) error) error {
+	_, err := withFailoverValue(ctx, defaultName, write, func(ctx context.Context) (struct{}, error) {
+		return struct{}{}, fn(ctx)
+	})
+	return err
+}
+
+// Similar to [withFailover], but fn returns a value.
+func withFailoverValue[T any](ctx context.Context, defaultName string, write bool, fn func(ctx context.Context) (T, error)) (T, error) {
+	v, err := fn(ctx)
+	if fctx, ok := failover(ctx, defaultName, write, err); ok {
+		return fn(fctx)
+	}
+	return v, err
+}
+
+// Returns the context on the fallback connection if the call should fail over, then logs and counts the failover.
+func failover(ctx context.Context, defaultName string, write bool, err error) (context.Context, bool) {
+	if ctx == nil || !isUnavailable(ctx, err) || ctx.Value(ctxKeyType_Failover{}) != nil {
+		return nil, false
+	}
+
+	name, err := ctxConnName(defaultName, ctx)
+	if err != nil {
+		return nil, false
+	}
+	conn := reg.conn(name)
+	if conn == nil || conn.config.FallbackConnection == "" || (write && !conn.config.FailoverWrites) {
+		return nil, false
+	}
+
+	fallback := conn.config.FallbackConnection
+	kind := "read"
+	if write {
+		kind = "write"
+	}
+	goutils.Warnf("redis[%s]: failing over the %s to `%s`: %s", name, kind, fallback, err)
+	Metrics()
+	metrics.failovers.WithLabelValues(name, fallback, kind).Inc()
+
+	return WithConnection(context.WithValue(ctx, ctxKeyType_Failover{}, name), fallback), true
+}
+
+// Returns true if the server of the connection can not serve the call, such as a timeout,
+// a refused connection or an open circuit breaker.
+func isUnavailable(ctx context.Context, err error) bool {
+	var circuitErr ErrCircuitOpen
+	return isBreakerFailure(ctx, err) || errors.As(err, &circuitErr)
+}
@@ -0,0 +1,105 @@
+package goredis_test
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hecigo/goredis"
+	"github.com/hecigo/goutils"
+	"github.com/prometheus/client_golang/prometheus"
+	. "gopkg.in/check.v1"
+)
+
+// The primary connection `failover` is unreachable, `failover2` is its fallback.
+type FailoverSuite struct {
+	registry *prometheus.Registry
+}
+
+var _ = Suite(&FailoverSuite{})
+
+func (s *FailoverSuite) SetUpSuite(c *C) {
+	fmt.Println("SetUpSuite > FailoverSuite")
+	goutils.QuickLoad()
+
+	s.registry = prometheus.NewRegistry()
+	c.Assert(s.registry.Register(goredis.Metrics()), IsNil)
+}
+
+func (s *FailoverSuite) TearDownTest(c *C) {
+	goredis.Close("failover", "failover2")
+}
+
+// Test the reads are served by the fallback connection, and the writes fail over only if allowed
+func (s *FailoverSuite) TestFailover(c *C) {
+	s.load(c, false)
+	ctx := goredis.WithConnection(context.Background(), "failover")
+	fallback := goredis.WithConnection(context.Background(), "failover2")
+	before := (&MetricsSuite{registry: s.registry}).gather(c)
+
+	c.Assert(goredis.Set(fallback, "test_failover", "secondary"), IsNil)
+	v, err := goredis.Get[string](ctx, "test_failover")
+	c.Assert(err, IsNil)
+	c.Assert(v, Equals, "secondary")
+
+	c.Assert(goredis.GetRankingBoard(fallback, "test_failover_zset").UpsertMulti(map[string]float64{"a": 1, "b": 2}), IsNil)
+	top, err := goredis.GetRankingBoard(ctx, "test_failover_zset").Top(1)
+	c.Assert(err, IsNil)
+	c.Assert(top, DeepEquals, map[string]float64{"b": 2})
+
+	c.Assert(goredis.SetCache(fallback, "test_failover_cache", "secondary"), IsNil)
+	var cached string
+	c.Assert(goredis.GetCache(ctx, "test_failover_cache", &cached), IsNil)
+	c.Assert(cached, Equals, "secondary")
+
+	// the writes do not fail over by default
+	c.Assert(goredis.Set(ctx, "test_failover", "primary"), ErrorMatches, ".*connection refused.*")
+	c.Assert(goredis.GetRankingBoard(ctx, "test_failover_zset").Remove("a"), ErrorMatches, ".*connection refused.*")
+
+	after := (&MetricsSuite{registry: s.registry}).gather(c)
+	key := `goredis_failovers_total{connection="failover",fallback="failover2",kind="read"}`
+	c.Assert(after[key]-before[key], Equals, 3.0)
+}
+
+// Test the writes fail over if the policy allows
+func (s *FailoverSuite) TestWrites(c *C) {
+	s.load(c, true)
+	ctx := goredis.WithConnection(context.Background(), "failover")
+	fallback := goredis.WithConnection(context.Background(), "failover2")
+
+	c.Assert(goredis.Set(ctx, "test_failover_write", "primary"), IsNil)
+	v, err := goredis.Get[string](fallback, "test_failover_write")
+	c.Assert(err, IsNil)
+	c.Assert(v, Equals, "primary")
+
+	c.Assert(goredis.MSet(ctx, map[string]interface{}{"test_failover_write2": "primary"}), IsNil)
+	_, err = goredis.GetRankingBoard(ctx, "test_failover_write_zset").IncrBy("a", 1)
+	c.Assert(err, IsNil)
+	score, err := goredis.GetRankingBoard(fallback, "test_failover_write_zset").Score("a")
+	c.Assert(err, IsNil)
+	c.Assert(score > 0, Equals, true)
+}
+
+// Test a connection can not fall back to itself
+func (s *FailoverSuite) TestValidation(c *C) {
+	err := goredis.OpenWithConfig(&goredis.Config{ConnectionName: "failover", KeyPrefix: "test-failover", FallbackConnection: "failover"})
+	c.Assert(err, ErrorMatches, ".*fallback connection must not be the connection itself.*")
+}
+
+// Load the connections and their caches, the primary fails over to the fallback
+func (s *FailoverSuite) load(c *C, writes bool) {
+	path := writeConfigFile(c, "redis.yaml", fmt.Sprintf(`
+connections:
+  failover:
+    addresses: [localhost:1]
+    key_prefix: test-failover
+    max_retries: -1
+    fallback_connection: failover2
+    failover_writes: %t
+  failover2:
+    key_prefix: test-failover
+caches:
+  failover:
+  failover2:
+`, writes))
+	c.Assert(goredis.LoadConfigFile(path), IsNil)
+}
@@ -0,0 +1,174 @@
+// Package goredistest runs an in-process Redis server for the tests of goredis and of the applications using it,
+// so that they run offline. The server is [miniredis], which covers strings, hashes, lists, sets, sorted sets,
+// TTLs, pipelines and MULTI.
+//
+//	func TestCheckout(t *testing.T) {
+//		srv := goredistest.New(t, "default")
+//		ctx := srv.Context(context.Background())
+//		if err := goredis.Set(ctx, "cart", cart); err != nil {
+//			t.Fatal(err)
+//		}
+//	}
+//
+// The keys do not expire by themselves, call [Server].FastForward to move the clock of the server.
+//
+// [miniredis]: https://github.com/alicebob/miniredis
+package goredistest
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/hecigo/goredis"
+)
+
+// The sequence of the key prefixes, so that the servers of a process never share a prefix.
+var seq atomic.Int64
+
+// An in-process Redis server. The methods of [miniredis.Miniredis] are available to seed and inspect the data,
+// or to move the clock of the server.
+type Server struct {
+	*miniredis.Miniredis
+
+	name   string
+	prefix string
+
+	mu      sync.Mutex
+	aliases []net.Listener
+}
+
+// Run an in-process Redis server listening at addr, or at a random port of localhost if addr is empty.
+// It does not open a connection, see [Start].
+func Run(addr string) (*Server, error) {
+	m := miniredis.NewMiniRedis()
+	if addr == "" {
+		addr = "127.0.0.1:0"
+	}
+	if err := m.StartAddr(addr); err != nil {
+		return nil, fmt.Errorf("goredistest: %w", err)
+	}
+	return &Server{Miniredis: m}, nil
+}
+
+// Run an in-process Redis server, then open it as the connection with name and a unique key prefix,
+// and enable its cache. If name is empty, the default connection will be used.
+// The connection replaces the opened one with the same name, see [goredis.OpenWithConfig].
+func Start(name string) (*Server, error) {
+	if name == "" {
+		name = "default"
+	}
+
+	s, err := Run("")
+	if err != nil {
+		return nil, err
+	}
+	s.name = name
+	s.prefix = fmt.Sprintf("goredistest-%s-%d", name, seq.Add(1))
+
+	err = goredis.OpenWithConfig(&goredis.Config{
+		ConnectionName: name,
+		Mode:           goredis.Mode_Standalone,
+		Addresses:      []string{s.Addr()},
+		KeyPrefix:      s.prefix,
+	})
+	if err == nil {
+		err = goredis.EnableCacheWithConfig(name, nil)
+	}
+	if err != nil {
+		s.Close()
+		return nil, fmt.Errorf("goredistest: %w", err)
+	}
+	return s, nil
+}
+
+// Similar to [Start], but fails tb if the server can not start,
+// then closes the connection and the server when tb and its subtests end.
+func New(tb testing.TB, name string) *Server {
+	tb.Helper()
+
+	s, err := Start(name)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	tb.Cleanup(s.Close)
+	return s
+}
+
+// Returns the connection name, empty if the server is not opened as a connection.
+func (s *Server) Name() string {
+	return s.name
+}
+
+// Returns the key prefix of the connection, empty if the server is not opened as a connection.
+func (s *Server) Prefix() string {
+	return s.prefix
+}
+
+// Returns a copy of ctx on the connection of the server, see [goredis.WithConnection].
+func (s *Server) Context(ctx context.Context) context.Context {
+	return goredis.WithConnection(ctx, s.name)
+}
+
+// Serve the same data at addr too, e.g. as a replica of [goredis.Config].ReplicaAddresses
+// which is always in sync with the master.
+func (s *Server) Alias(addr string) error {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("goredistest: %w", err)
+	}
+
+	s.mu.Lock()
+	s.aliases = append(s.aliases, l)
+	s.mu.Unlock()
+
+	go s.forward(l)
+	return nil
+}
+
+// Forward the connections accepted by l to the server, until l is closed.
+func (s *Server) forward(l net.Listener) {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+
+		go func() {
+			defer conn.Close()
+			target, err := net.Dial("tcp", s.Addr())
+			if err != nil {
+				return
+			}
+			defer target.Close()
+
+			go func() {
+				io.Copy(target, conn)
+				target.Close()
+			}()
+			io.Copy(conn, target)
+		}()
+	}
+}
+
+// Close the connection and its cache, the aliases and the server.
+func (s *Server) Close() {
+	if s.name != "" {
+		goredis.DisableCache(s.name)
+		goredis.Close(s.name)
+	}
+
+	s.mu.Lock()
+	for _, l := range s.aliases {
+		l.Close()
+	}
+	s.aliases = nil
+	s.mu.Unlock()
+
+	s.Miniredis.Close()
+}
@@ -0,0 +1,116 @@
+package goredistest_test
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/hecigo/goredis"
+	"github.com/hecigo/goredis/goredistest"
+	. "gopkg.in/check.v1"
+)
+
+func Test(t *testing.T) { TestingT(t) }
+
+type ServerSuite struct{}
+
+var _ = Suite(&ServerSuite{})
+
+func (s *ServerSuite) SetUpSuite(c *C) {
+	fmt.Println("SetUpSuite > ServerSuite")
+}
+
+// Test the server is opened as a connection with a unique prefix and a cache
+func (s *ServerSuite) TestStart(c *C) {
+	srv, err := goredistest.Start("test")
+	c.Assert(err, IsNil)
+	defer srv.Close()
+	ctx := srv.Context(context.Background())
+
+	other, err := goredistest.Start("test2")
+	c.Assert(err, IsNil)
+	defer other.Close()
+	c.Assert(srv.Prefix(), Not(Equals), other.Prefix())
+
+	c.Assert(goredis.Set(ctx, "key", "value"), IsNil)
+	v, err := srv.Get(srv.Prefix() + ".key")
+	c.Assert(err, IsNil)
+	c.Assert(v, Equals, "value")
+
+	c.Assert(goredis.SetCache(ctx, "cached", "value"), IsNil)
+	var cached string
+	c.Assert(goredis.GetCache(ctx, "cached", &cached), IsNil)
+	c.Assert(cached, Equals, "value")
+
+	board := goredis.GetRankingBoard(ctx, "ranking")
+	c.Assert(board.UpsertMulti(map[string]float64{"a": 1, "b": 2}), IsNil)
+	top, err := board.Top(1)
+	c.Assert(err, IsNil)
+	c.Assert(top, DeepEquals, map[string]float64{"b": 2})
+
+	// pipelines and MULTI
+	client := goredis.Client(ctx)
+	cmds, err := client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Incr(ctx, "counter")
+		pipe.Incr(ctx, "counter")
+		return nil
+	})
+	c.Assert(err, IsNil)
+	c.Assert(cmds[1].(*redis.IntCmd).Val(), Equals, int64(2))
+
+	// the keys expire when the clock of the server moves
+	c.Assert(client.Set(ctx, "ttl", "value", time.Minute).Err(), IsNil)
+	srv.FastForward(time.Minute)
+	c.Assert(client.Exists(ctx, "ttl").Val(), Equals, int64(0))
+}
+
+// Test an alias serves the same data, e.g. as a replica
+func (s *ServerSuite) TestAlias(c *C) {
+	srv, err := goredistest.Run("")
+	c.Assert(err, IsNil)
+	defer srv.Close()
+	c.Assert(srv.Set("key", "value"), IsNil)
+
+	addr := freeAddr(c)
+	c.Assert(srv.Alias(addr), IsNil)
+	client := redis.NewClient(&redis.Options{Addr: addr})
+	defer client.Close()
+	c.Assert(client.Get(context.Background(), "key").Val(), Equals, "value")
+
+	_, err = goredistest.Run(srv.Addr())
+	c.Assert(err, ErrorMatches, "goredistest: .*address already in use")
+}
+
+// Test the connection is closed when the test ends
+func TestNew(t *testing.T) {
+	var ctx context.Context
+	t.Run("open", func(t *testing.T) {
+		srv := goredistest.New(t, "new")
+		ctx = srv.Context(context.Background())
+		if err := goredis.Set(ctx, "key", "value"); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	if _, err := goredis.ClientE(ctx); err != (goredis.ErrConnectionNotOpened{Name: "new"}) {
+		t.Fatalf("the connection is not closed: %v", err)
+	}
+	var v string
+	if err := goredis.GetCache(ctx, "key", &v); err != (goredis.ErrCacheNotEnabled{Name: "new"}) {
+		t.Fatalf("the cache is not disabled: %v", err)
+	}
+	if err := goredis.SetCache(ctx, "key", "value"); err != (goredis.ErrCacheNotEnabled{Name: "new"}) {
+		t.Fatalf("the cache is not disabled: %v", err)
+	}
+}
+
+// Returns an address of localhost which is free to listen.
+func freeAddr(c *C) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	c.Assert(err, IsNil)
+	defer l.Close()
+	return l.Addr().String()
+}
@@ -6,7 +6,6 @@ import (
 
 	"errors"
 	"reflect"
-	"strings"
 
 	"github.com/go-redis/redis/v8"
 	"github.com/hecigo/goutils"
@@ -49,7 +48,16 @@ const (
 //
 //     - Including [CtxKey_DataType] to specify the data type of the Redis key.
 //
-//  3. [keys]: the key without prefix.
+//     - Including [CtxKey_ReadPreference] to read from the master or replicas, see [Config].ReadOnly.
+//
+//     - Including [CtxKey_KeyPrefix] to replace the key prefix of the connection,
+//     and [CtxKey_KeySegments] to append segments to it, e.g. to scope the keys by tenant.
+//     Including [CtxKey_RawKeys] to use the keys without prefix. See [KeyBuilder].
+//
+//     The helpers such as [WithConnection], [WithDataType] and [WithKeyPrefix] set these values with the right types.
+//     A value of a wrong type is returned as an error.
+//
+//  3. [keys]: the key without prefix, the slice is not modified.
 //
 // # Notes:
 //
@@ -64,11 +72,29 @@ const (
 //     - [CtxKey_SliceStop]: the stop index of the range
 //
 //     - [CtxKey_SliceReverse]: true (default) to order the sorted-set by descending of score
+//
+//     Or use [WithRange] and [WithReverse].
+//
+//  4. If the connection is unavailable, the keys are read again from [Config].FallbackConnection.
+//     The result is compared with [Config].ShadowConnection if [Config].ShadowCompareReads is enabled.
 func Get[T any](ctx context.Context, keys ...string) (interface{}, error) {
+	keys = append([]string(nil), keys...) // read again by the shadow in the background
+	return withShadowRead(ctx, "default", "Get", func(ctx context.Context) (interface{}, error) {
+		return get[T](ctx, keys...)
+	})
+}
+
+// Get the value(s) on the connection of ctx, see [Get].
+func get[T any](ctx context.Context, keys ...string) (interface{}, error) {
 	if len(keys) == 0 {
 		return nil, errors.New("keys is empty")
 	}
 
+	dataType, err := ctxDataType(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	// get type of T
 	var t T
 	tKind := reflect.TypeOf(t).Kind()
@@ -80,7 +106,7 @@ func Get[T any](ctx context.Context, keys ...string) (interface{}, error) {
 
 	// map[string]any or struct
 	case reflect.Map, reflect.Struct:
-		switch ctx.Value(CtxKey_DataType) {
+		switch dataType {
 		case HASH:
 			return getHash[T](ctx, keys...)
 		default:
@@ -89,7 +115,7 @@ func Get[T any](ctx context.Context, keys ...string) (interface{}, error) {
 
 	// slice
 	case reflect.Slice:
-		switch ctx.Value(CtxKey_DataType) {
+		switch dataType {
 		case SET:
 			return getSet[T](ctx, keys...)
 		case ZSET:
@@ -127,7 +153,17 @@ func Get[T any](ctx context.Context, keys ...string) (interface{}, error) {
 //  1. This function does not support to set value to Redis [ZSET] because [ZSET] is a special data type.
 //
 //  2. This function will delete the old key first, then set the new list/set, sothat should not use it to set a long list/set.
+//
+//  3. If the connection is unavailable, the value is set to [Config].FallbackConnection only if [Config].FailoverWrites is enabled.
+//     The value is mirrored to [Config].ShadowConnection in the background after it is set.
 func Set(ctx context.Context, key string, value interface{}, expiration ...time.Duration) error {
+	return withShadow(ctx, "default", "Set", func(ctx context.Context) error {
+		return set(ctx, key, value, expiration...)
+	})
+}
+
+// Set the value on the connection of ctx, see [Set].
+func set(ctx context.Context, key string, value interface{}, expiration ...time.Duration) error {
 	if key == "" {
 		return errors.New("key is empty")
 	}
@@ -140,6 +176,11 @@ func Set(ctx context.Context, key string, value interface{}, expiration ...time.
 		expi = expiration[0]
 	}
 
+	dataType, err := ctxDataType(ctx)
+	if err != nil {
+		return err
+	}
+
 	// get type of value
 	tKind := reflect.TypeOf(value).Kind()
 
@@ -150,7 +191,7 @@ func Set(ctx context.Context, key string, value interface{}, expiration ...time.
 
 	// map[string]any or struct
 	case reflect.Map, reflect.Struct:
-		switch ctx.Value(CtxKey_DataType) {
+		switch dataType {
 		case HASH:
 			return setHash(ctx, key, value, expi)
 		default:
@@ -159,7 +200,7 @@ func Set(ctx context.Context, key string, value interface{}, expiration ...time.
 
 	// slice
 	case reflect.Slice:
-		switch ctx.Value(CtxKey_DataType) {
+		switch dataType {
 		case SET:
 			return setSet(ctx, key, value, expi)
 		case LIST:
@@ -185,12 +226,31 @@ func Set(ctx context.Context, key string, value interface{}, expiration ...time.
 //
 //	ctx := context.WithValue(context.Background(), goredis.CtxKey_DataType, goredis.HASH)
 //	MSet(ctx, keyValues)
+//
+// It fails over and is mirrored as [Set].
 func MSet(ctx context.Context, keyValues map[string]interface{}, expiration ...time.Duration) error {
+	// copied, the shadow writes it in the background
+	kv := make(map[string]interface{}, len(keyValues))
+	for k, v := range keyValues {
+		kv[k] = v
+	}
+	return withShadow(ctx, "default", "MSet", func(ctx context.Context) error {
+		return mSet(ctx, kv, expiration...)
+	})
+}
+
+// Set the values on the connection of ctx, see [MSet].
+func mSet(ctx context.Context, keyValues map[string]interface{}, expiration ...time.Duration) error {
 	var expi time.Duration = 0 // never expire
 	if len(expiration) > 1 {
 		expi = expiration[0]
 	}
 
+	dataType, err := ctxDataType(ctx)
+	if err != nil {
+		return err
+	}
+
 	// get first value of keyValues
 	var elKind reflect.Kind
 	for _, v := range keyValues {
@@ -205,7 +265,7 @@ func MSet(ctx context.Context, keyValues map[string]interface{}, expiration ...t
 
 	// map[string]any or struct
 	case reflect.Map, reflect.Struct:
-		switch ctx.Value(CtxKey_DataType) {
+		switch dataType {
 		case HASH:
 			return setMultiHash(ctx, keyValues, expi)
 		default:
@@ -214,7 +274,7 @@ func MSet(ctx context.Context, keyValues map[string]interface{}, expiration ...t
 
 	// slice
 	case reflect.Slice:
-		switch ctx.Value(CtxKey_DataType) {
+		switch dataType {
 		case SET:
 			return setMultiSet(ctx, keyValues, expi)
 		case LIST:
@@ -228,30 +288,17 @@ func MSet(ctx context.Context, keyValues map[string]interface{}, expiration ...t
 	}
 }
 
-// add key prefix to the given key
-func addKeyPrefix(ctx context.Context, key ...string) []string {
-	if len(key) == 0 {
-		goutils.Panic("key is empty")
-	}
-
-	cfg := GetConfig(ctx)
-	for i, k := range key {
-		key[i] = cfg.KeyPrefix + "." + k
-	}
-	return key
-}
-
-// remove key prefix from the given key
-func removeKeyPrefix(ctx context.Context, key ...string) []string {
-	if len(key) == 0 {
-		goutils.Panic("key is empty")
+// Returns the connection of ctx and the key builder of the call on it.
+func connAndKeys(ctx context.Context) (*connection, KeyBuilder, error) {
+	conn, err := getConn(ctx)
+	if err != nil {
+		return nil, KeyBuilder{}, err
 	}
-
-	cfg := GetConfig(ctx)
-	for i, k := range key {
-		key[i] = strings.TrimPrefix(k, cfg.KeyPrefix+".")
+	kb, err := newKeyBuilder(ctx, conn.config)
+	if err != nil {
+		return nil, KeyBuilder{}, err
 	}
-	return key
+	return conn, kb, nil
 }
 
 // Get string(s) from Redis.
@@ -260,9 +307,14 @@ func getString(ctx context.Context, keys ...string) (interface{}, error) {
 		return nil, errors.New("key is empty")
 	}
 
+	conn, kb, err := connAndKeys(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	// get single key-value
 	if len(keys) == 1 {
-		val, err := Client(ctx).Get(ctx, addKeyPrefix(ctx, keys...)[0]).Result()
+		val, err := conn.reader(ctx).Get(ctx, kb.Key(keys[0])).Result()
 		if err != nil {
 			if err == redis.Nil {
 				// redis.Nil means the key does not exist, so we just return nil
@@ -274,7 +326,7 @@ func getString(ctx context.Context, keys ...string) (interface{}, error) {
 	}
 
 	// get multiple key-values
-	val, err := Client(ctx).MGet(ctx, addKeyPrefix(ctx, keys...)...).Result()
+	val, err := conn.reader(ctx).MGet(ctx, kb.Keys(keys...)...).Result()
 
 	// error
 	if err != nil {
@@ -287,7 +339,7 @@ func getString(ctx context.Context, keys ...string) (interface{}, error) {
 
 	// result
 	r := make(map[string]*string)
-	for i, k := range removeKeyPrefix(ctx, keys...) {
+	for i, k := range keys {
 		v := val[i]
 		if v == nil {
 			r[k] = nil
@@ -318,12 +370,17 @@ func getVariousKind[T any](ctx context.Context, keys ...string) (interface{}, er
 
 // Set any value to Redis as string.
 func setVariousKind(ctx context.Context, key string, value interface{}, expiration time.Duration) error {
-	value, err := goutils.AnyToStr(value)
+	conn, kb, err := connAndKeys(ctx)
+	if err != nil {
+		return err
+	}
+
+	value, err = goutils.AnyToStr(value)
 	if err != nil {
 		return err
 	}
 
-	status, err := Client(ctx).Set(ctx, addKeyPrefix(ctx, key)[0], value, expiration).Result()
+	status, err := conn.client.Set(ctx, kb.Key(key), value, expiration).Result()
 	if err != nil {
 		return err
 	}
@@ -338,23 +395,23 @@ func setVariousKind(ctx context.Context, key string, value interface{}, expirati
 
 // Set multiple key-values to Redis as string.
 func setMultiVariousKind(ctx context.Context, keyValues map[string]interface{}, expiration time.Duration) error {
-	// convert time.Time to string
+	conn, kb, err := connAndKeys(ctx)
+	if err != nil {
+		return err
+	}
+
+	// add key prefix, convert time.Time to string and keyValues to slice
+	var kv []interface{}
 	for k, v := range keyValues {
 		v, err := goutils.AnyToStr(v)
 		if err != nil {
 			return err
 		}
-		keyValues[k] = v
-	}
-
-	// add key prefix and convert keyValues to slice
-	var kv []interface{}
-	for k, v := range keyValues {
-		kv = append(kv, addKeyPrefix(ctx, k)[0], v)
+		kv = append(kv, kb.Key(k), v)
 	}
 
 	// set
-	status, err := Client(ctx).MSet(ctx, kv).Result()
+	status, err := conn.client.MSet(ctx, kv).Result()
 	if err != nil {
 		return err
 	}
@@ -372,13 +429,18 @@ func getHash[T any](ctx context.Context, keys ...string) (interface{}, error) {
 		return nil, errors.New("key is empty")
 	}
 
+	conn, kb, err := connAndKeys(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	// T is struct
 	var t T
 	tIsTruct := reflect.TypeOf(t).Kind() == reflect.Struct
 
 	// get single key-value
 	if len(keys) == 1 {
-		val, err := Client(ctx).HGetAll(ctx, addKeyPrefix(ctx, keys...)[0]).Result()
+		val, err := conn.reader(ctx).HGetAll(ctx, kb.Key(keys[0])).Result()
 		if err != nil {
 			if err == redis.Nil {
 				// redis.Nil means the key does not exist, so we just return nil
@@ -394,8 +456,8 @@ func getHash[T any](ctx context.Context, keys ...string) (interface{}, error) {
 	}
 
 	// get multiple key-values
-	cmds, err := Client(ctx).TxPipelined(ctx, func(pipe redis.Pipeliner) error {
-		for _, k := range addKeyPrefix(ctx, keys...) {
+	cmds, err := conn.readPipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, k := range kb.Keys(keys...) {
 			pipe.HGetAll(ctx, k)
 		}
 		return nil
@@ -408,7 +470,7 @@ func getHash[T any](ctx context.Context, keys ...string) (interface{}, error) {
 	}
 
 	r := make(map[string]interface{})
-	for i, k := range removeKeyPrefix(ctx, keys...) {
+	for i, k := range keys {
 		c := cmds[i].(*redis.StringStringMapCmd)
 		err := c.Err()
 		if err != nil {
@@ -438,6 +500,10 @@ func getHash[T any](ctx context.Context, keys ...string) (interface{}, error) {
 
 // Set hash to Redis. The value must be a struct or a map.
 func setHash(ctx context.Context, key string, value interface{}, expiration time.Duration) (err error) {
+	conn, kb, err := connAndKeys(ctx)
+	if err != nil {
+		return err
+	}
 
 	// convert value to map[string]string
 	var temp map[string]string
@@ -467,8 +533,8 @@ func setHash(ctx context.Context, key string, value interface{}, expiration time
 	}
 
 	// set key-value
-	key = addKeyPrefix(ctx, key)[0]
-	cmd := Client(ctx).HMSet(ctx, key, val...)
+	key = kb.Key(key)
+	cmd := conn.client.HMSet(ctx, key, val...)
 	err = cmd.Err()
 	if err != nil || !cmd.Val() {
 		goutils.Errorf("%s", cmd.String())
@@ -477,12 +543,17 @@ func setHash(ctx context.Context, key string, value interface{}, expiration time
 	}
 
 	// set expiration
-	err = setExpiration(ctx, key, expiration)
+	err = setExpiration(ctx, conn.client, key, expiration)
 	return err
 }
 
 // Similar to [setHash], but support multiple key-values with pipeline.
 func setMultiHash(ctx context.Context, keyValues map[string]interface{}, expiration time.Duration) (err error) {
+	conn, kb, err := connAndKeys(ctx)
+	if err != nil {
+		return err
+	}
+
 	// convert keyValues to map[string][]interface{}
 	temp := make(map[string][]interface{})
 	for key, value := range keyValues {
@@ -515,9 +586,9 @@ func setMultiHash(ctx context.Context, keyValues map[string]interface{}, expirat
 	}
 
 	// set key-values
-	cmds, err := Client(ctx).TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+	cmds, err := conn.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
 		for key, val := range temp {
-			key = addKeyPrefix(ctx, key)[0]
+			key = kb.Key(key)
 			pipe.HMSet(ctx, key, val...)
 			if expiration > 0 {
 				pipe.Expire(ctx, key, expiration)
@@ -548,67 +619,74 @@ func getList[T any](ctx context.Context, keys ...string) (interface{}, error) {
 		return nil, errors.New("key is empty")
 	}
 
-	var (
-		t     T
-		start int64 = 0
-		stop  int64 = -1
-	)
+	conn, kb, err := connAndKeys(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var t T
 
 	// get list start and stop from context
-	i := ctx.Value(CtxKey_SliceStart)
-	if i != nil {
-		start = i.(int64)
-	}
-	i = ctx.Value(CtxKey_SliceStop)
-	if i != nil {
-		stop = i.(int64)
+	start, stop, err := ctxRange(ctx)
+	if err != nil {
+		return nil, err
 	}
 
 	// get single key-value
 	if len(keys) == 1 {
-		cmd := Client(ctx).LRange(ctx, addKeyPrefix(ctx, keys...)[0], start, stop)
+		cmd := conn.reader(ctx).LRange(ctx, kb.Key(keys[0]), start, stop)
 		return redisCmdToSlice[T](ctx, reflect.TypeOf(t).Elem(), cmd)
 	}
 
 	// get multiple key-values
-	cmds, err := Client(ctx).TxPipelined(ctx, func(pipe redis.Pipeliner) error {
-		for _, k := range addKeyPrefix(ctx, keys...) {
+	cmds, err := conn.readPipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, k := range kb.Keys(keys...) {
 			pipe.LRange(ctx, k, start, stop)
 		}
 		return nil
 	})
 
-	return redisCmdToMap[T](ctx, reflect.TypeOf(t).Elem(), keys, cmds, err)
+	return redisCmdToMap[T](reflect.TypeOf(t).Elem(), keys, cmds, err)
 }
 
 // Set list to Redis. The value must be a slice.
 // This action will delete the old list and set a new one.
 func setList(ctx context.Context, key string, value interface{}, expiration time.Duration) error {
+	conn, kb, err := connAndKeys(ctx)
+	if err != nil {
+		return err
+	}
+
 	val, err := goutils.Unmarshal[[]interface{}](value)
 	if err != nil {
 		return err
 	}
-	key = addKeyPrefix(ctx, key)[0]
+	key = kb.Key(key)
 
 	// delete old list
-	_, err = Client(ctx).Del(ctx, key).Result()
+	_, err = conn.client.Del(ctx, key).Result()
 	if err != nil {
 		return err
 	}
 
 	// set new list
-	_, err = Client(ctx).RPush(ctx, key, val...).Result()
+	_, err = conn.client.RPush(ctx, key, val...).Result()
 	if err != nil {
 		return err
 	}
 
 	// set expiration
-	err = setExpiration(ctx, key, expiration)
+	err = setExpiration(ctx, conn.client, key, expiration)
 	return err
 }
 
 // Similar to [setList], but support multiple key-values with pipeline.
 func setMultiList(ctx context.Context, keyValues map[string]interface{}, expiration time.Duration) (err error) {
+	conn, kb, err := connAndKeys(ctx)
+	if err != nil {
+		return err
+	}
+
 	// convert keyValues to map[string][]interface{}
 	temp := make(map[string][]interface{})
 	for key, value := range keyValues {
@@ -620,9 +698,9 @@ func setMultiList(ctx context.Context, keyValues map[string]interface{}, expirat
 	}
 
 	// set key-values
-	cmds, err := Client(ctx).TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+	cmds, err := conn.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
 		for key, val := range temp {
-			key := addKeyPrefix(ctx, key)[0]
+			key := kb.Key(key)
 			pipe.Del(ctx, key)
 			pipe.RPush(ctx, key, val...)
 			if expiration > 0 {
@@ -653,53 +731,68 @@ func getSet[T any](ctx context.Context, keys ...string) (interface{}, error) {
 		return nil, errors.New("key is empty")
 	}
 
+	conn, kb, err := connAndKeys(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	var t T
 
 	// get single key-value
 	if len(keys) == 1 {
-		cmd := Client(ctx).SMembers(ctx, addKeyPrefix(ctx, keys...)[0])
+		cmd := conn.reader(ctx).SMembers(ctx, kb.Key(keys[0]))
 		return redisCmdToSlice[T](ctx, reflect.TypeOf(t).Elem(), cmd)
 	}
 
 	// get multiple key-values
-	cmds, err := Client(ctx).TxPipelined(ctx, func(pipe redis.Pipeliner) error {
-		for _, k := range addKeyPrefix(ctx, keys...) {
+	cmds, err := conn.readPipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, k := range kb.Keys(keys...) {
 			pipe.SMembers(ctx, k)
 		}
 		return nil
 	})
 
-	return redisCmdToMap[T](ctx, reflect.TypeOf(t).Elem(), keys, cmds, err)
+	return redisCmdToMap[T](reflect.TypeOf(t).Elem(), keys, cmds, err)
 }
 
 // Set set to Redis. The value must be a slice.
 // This action will delete the old set and set a new one.
 func setSet(ctx context.Context, key string, value interface{}, expiration time.Duration) error {
+	conn, kb, err := connAndKeys(ctx)
+	if err != nil {
+		return err
+	}
+
 	val, err := goutils.Unmarshal[[]interface{}](value)
 	if err != nil {
 		return err
 	}
-	key = addKeyPrefix(ctx, key)[0]
+	key = kb.Key(key)
 
 	// delete old set
-	_, err = Client(ctx).Del(ctx, key).Result()
+	_, err = conn.client.Del(ctx, key).Result()
 	if err != nil {
 		return err
 	}
 
 	// set new set
-	_, err = Client(ctx).SAdd(ctx, key, val...).Result()
+	_, err = conn.client.SAdd(ctx, key, val...).Result()
 	if err != nil {
 		return err
 	}
 
 	// set expiration
-	err = setExpiration(ctx, key, expiration)
+	err = setExpiration(ctx, conn.client, key, expiration)
 	return err
 }
 
 // Similar to [setSet], but support multiple key-values with pipeline.
 func setMultiSet(ctx context.Context, keyValues map[string]interface{}, expiration time.Duration) (err error) {
+	conn, kb, err := connAndKeys(ctx)
+	if err != nil {
+		return err
+	}
+
 	// convert keyValues to map[string][]interface{}
 	temp := make(map[string][]interface{})
 	for key, value := range keyValues {
@@ -711,9 +804,9 @@ func setMultiSet(ctx context.Context, keyValues map[string]interface{}, expirati
 	}
 
 	// set key-values
-	cmds, err := Client(ctx).TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+	cmds, err := conn.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
 		for key, val := range temp {
-			key := addKeyPrefix(ctx, key)[0]
+			key := kb.Key(key)
 			pipe.Del(ctx, key)
 			pipe.SAdd(ctx, key, val...)
 			if expiration > 0 {
@@ -745,31 +838,27 @@ func getSortedSet[T any](ctx context.Context, keys ...string) (interface{}, erro
 		return nil, errors.New("key is empty")
 	}
 
-	var (
-		t     T
-		start int64 = 0
-		stop  int64 = -1
-		rev         = true
-	)
-
-	// get sorted-set start and stop from context
-	i := ctx.Value(CtxKey_SliceStart)
-	if i != nil {
-		start = i.(int64)
+	conn, kb, err := connAndKeys(ctx)
+	if err != nil {
+		return nil, err
 	}
-	i = ctx.Value(CtxKey_SliceStop)
-	if i != nil {
-		stop = i.(int64)
+
+	var t T
+
+	// get sorted-set start, stop and order from context
+	start, stop, err := ctxRange(ctx)
+	if err != nil {
+		return nil, err
 	}
-	i = ctx.Value(CtxKey_SliceReverse)
-	if i != nil {
-		rev = i.(bool)
+	rev, err := ctxReverse(ctx)
+	if err != nil {
+		return nil, err
 	}
 
 	// get single key-value with ZRangeArgs
 	if len(keys) == 1 {
-		cmd := Client(ctx).ZRangeArgs(ctx, redis.ZRangeArgs{
-			Key:   addKeyPrefix(ctx, keys...)[0],
+		cmd := conn.reader(ctx).ZRangeArgs(ctx, redis.ZRangeArgs{
+			Key:   kb.Key(keys[0]),
 			Start: start,
 			Stop:  stop,
 			Rev:   rev,
@@ -778,8 +867,8 @@ func getSortedSet[T any](ctx context.Context, keys ...string) (interface{}, erro
 	}
 
 	// get multiple key-values
-	cmds, err := Client(ctx).TxPipelined(ctx, func(pipe redis.Pipeliner) error {
-		for _, k := range addKeyPrefix(ctx, keys...) {
+	cmds, err := conn.readPipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, k := range kb.Keys(keys...) {
 			pipe.ZRangeArgs(ctx, redis.ZRangeArgs{
 				Key:   k,
 				Start: start,
@@ -790,7 +879,7 @@ func getSortedSet[T any](ctx context.Context, keys ...string) (interface{}, erro
 		return nil
 	})
 
-	return redisCmdToMap[T](ctx, reflect.TypeOf(t).Elem(), keys, cmds, err)
+	return redisCmdToMap[T](reflect.TypeOf(t).Elem(), keys, cmds, err)
 }
 
 // read redis command result to slice
@@ -816,7 +905,7 @@ func redisCmdToSlice[T any](ctx context.Context, eleType reflect.Type, cmd *redi
 }
 
 // read redis command result to map
-func redisCmdToMap[T any](ctx context.Context, eleType reflect.Type, keys []string, cmds []redis.Cmder, connErr error) (interface{}, error) {
+func redisCmdToMap[T any](eleType reflect.Type, keys []string, cmds []redis.Cmder, connErr error) (interface{}, error) {
 	if connErr != nil {
 		if connErr == redis.Nil {
 			return nil, nil
@@ -825,7 +914,7 @@ func redisCmdToMap[T any](ctx context.Context, eleType reflect.Type, keys []stri
 	}
 
 	r := make(map[string]interface{})
-	for i, k := range removeKeyPrefix(ctx, keys...) {
+	for i, k := range keys {
 		c := cmds[i].(*redis.StringSliceCmd)
 		err := c.Err()
 		if err != nil {
@@ -857,12 +946,12 @@ func redisCmdToMap[T any](ctx context.Context, eleType reflect.Type, keys []stri
 }
 
 // set expiration for key
-func setExpiration(ctx context.Context, key string, expiration time.Duration) error {
+func setExpiration(ctx context.Context, client redis.UniversalClient, key string, expiration time.Duration) error {
 	if expiration == 0 {
 		return nil
 	}
 
-	cmd := Client(ctx).Expire(ctx, key, expiration)
+	cmd := client.Expire(ctx, key, expiration)
 	err := cmd.Err()
 	if err != nil || !cmd.Val() {
 		goutils.Errorf("%s", cmd.String())
@@ -2,6 +2,7 @@ package goredis_test
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"time"
 
@@ -27,7 +28,34 @@ type Geo struct {
 func (s *HandlerSuite) SetUpSuite(c *C) {
 	fmt.Println("SetUpSuite > HandlerSuite")
 	goutils.QuickLoad()
-	goredis.Open()
+	c.Assert(goredis.Open(), IsNil)
+
+	// seed the keys which the tests only read
+	ctx := context.Background()
+	geo := Geo{Loc: "10.757437,106.6794102", Unit: "km", DistanceType: "plane"}
+	c.Assert(goredis.MSet(goredis.WithDataType(ctx, goredis.HASH), map[string]interface{}{
+		"test_hash_int":     map[string]int{"k1": 1, "k2": 2},
+		"test_hash_int2":    map[string]int{"k3": 3, "k4": 4},
+		"test_hash_struct3": geo,
+		"test_hash_struct4": geo,
+	}), IsNil)
+	kb, err := goredis.GetKeyBuilder(ctx)
+	c.Assert(err, IsNil)
+	item, err := json.Marshal(TestStruct{geo})
+	c.Assert(err, IsNil)
+	for _, key := range kb.Keys("test_slice_list_struct1", "test_slice_list_struct2") {
+		c.Assert(goredis.Client().Del(ctx, key).Err(), IsNil)
+		c.Assert(goredis.Client().RPush(ctx, key, item, item).Err(), IsNil)
+	}
+	c.Assert(goredis.MSet(goredis.WithDataType(ctx, goredis.SET), map[string]interface{}{
+		"test_slice_set1": []int{1, 2},
+		"test_slice_set2": []int{3, 4},
+	}), IsNil)
+	c.Assert(goredis.MSet(ctx, map[string]interface{}{
+		"test_slice_string":      []string{"v1", "v2"},
+		"test_slice_string_int":  []int{1, 2},
+		"test_slice_string_int2": []int{3, 4},
+	}), IsNil)
 }
 
 func (s *HandlerSuite) TearDownSuite(c *C) {
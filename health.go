@@ -0,0 +1,196 @@
+package goredis
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	json "github.com/goccy/go-json"
+	"github.com/hecigo/goutils"
+)
+
+// The health status of a connection, reported by [HealthCheck].
+type HealthStatus struct {
+	// The connection name.
+	Name string `json:"name"`
+
+	// True if the server replies PING.
+	Healthy bool `json:"healthy"`
+
+	// The round-trip time of PING.
+	Latency time.Duration `json:"latency"`
+
+	// The replication role of the server, such as `master` or `slave`.
+	// It is empty if the role can not be detected, e.g. in cluster mode.
+	Role string `json:"role,omitempty"`
+
+	// The statistics of the connection pool.
+	PoolStats *redis.PoolStats `json:"pool_stats,omitempty"`
+
+	// The state of the circuit breaker, empty if it is disabled. See [Config].BreakerThreshold.
+	Breaker BreakerState `json:"breaker,omitempty"`
+
+	// The error of PING, empty if healthy.
+	Error string `json:"error,omitempty"`
+}
+
+// Ping the Redis server of the connection with name. If name is empty, the default connection will be used.
+func Ping(ctx context.Context, name string) error {
+	if name == "" {
+		name = "default"
+	}
+
+	conn := reg.conn(name)
+	if conn == nil {
+		return ErrConnectionNotOpened{Name: name}
+	}
+	return conn.client.Ping(ctx).Err()
+}
+
+// Returns the statistics of the connection pool with name, such as the number of hits, misses, timeouts
+// and the idle connections. If name is empty, the default connection will be used.
+// A growing number of timeouts means the pool is exhausted, consider increasing [Config].PoolSize.
+func PoolStats(name string) (*redis.PoolStats, error) {
+	if name == "" {
+		name = "default"
+	}
+
+	conn := reg.conn(name)
+	if conn == nil {
+		return nil, ErrConnectionNotOpened{Name: name}
+	}
+	return conn.client.PoolStats(), nil
+}
+
+// Returns the state of the circuit breaker of the connection with name, see [Config].BreakerThreshold.
+// It is [BreakerState_Disabled] if the connection has no circuit breaker. If name is empty, the default connection will be used.
+func CircuitBreakerState(name string) (BreakerState, error) {
+	if name == "" {
+		name = "default"
+	}
+
+	conn := reg.conn(name)
+	if conn == nil {
+		return BreakerState_Disabled, ErrConnectionNotOpened{Name: name}
+	}
+	return conn.breaker.State(), nil
+}
+
+// Check the health of every opened connection concurrently. The result is sorted by connection name.
+func HealthCheck(ctx context.Context) []HealthStatus {
+	conns := reg.connList()
+	result := make([]HealthStatus, len(conns))
+
+	var wg sync.WaitGroup
+	for i, conn := range conns {
+		wg.Add(1)
+		go func(i int, conn *connection) {
+			defer wg.Done()
+			result[i] = conn.healthCheck(ctx)
+		}(i, conn)
+	}
+	wg.Wait()
+
+	return result
+}
+
+// Check the health of the connection.
+func (conn *connection) healthCheck(ctx context.Context) HealthStatus {
+	status := HealthStatus{
+		Name:      conn.config.ConnectionName,
+		PoolStats: conn.client.PoolStats(),
+	}
+
+	start := time.Now()
+	err := conn.client.Ping(ctx).Err()
+	status.Latency = time.Since(start)
+	status.Breaker = conn.breaker.State()
+	if err != nil {
+		status.Error = err.Error()
+		return status
+	}
+	status.Healthy = true
+
+	// the role is informative only, failing to detect it does not make the connection unhealthy
+	if mode := conn.config.resolvedMode(); mode != Mode_Cluster && mode != Mode_Ring {
+		info, err := conn.client.Info(ctx, "replication").Result()
+		if err == nil {
+			status.Role = parseRole(info)
+		}
+	}
+
+	return status
+}
+
+// Parse the `role` field from the output of `INFO replication`.
+func parseRole(info string) string {
+	for _, line := range strings.Split(info, "\n") {
+		if role, ok := strings.CutPrefix(strings.TrimSpace(line), "role:"); ok {
+			return role
+		}
+	}
+	return ""
+}
+
+// Returns a [http.Handler] for liveness and readiness probes, it can be mounted at any path:
+//
+//   - `GET .../livez`: always 200, the process is alive as long as it can serve the request.
+//
+//   - `GET .../readyz`: 200 if every connection is healthy, otherwise 503. The body is the JSON of [HealthCheck].
+//
+// Example:
+//
+//	http.Handle("/health/", goredis.HealthHandler())
+func HealthHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/livez"):
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte("ok"))
+
+		case strings.HasSuffix(r.URL.Path, "/readyz"):
+			statuses := HealthCheck(r.Context())
+			code := http.StatusOK
+			for _, status := range statuses {
+				if !status.Healthy {
+					code = http.StatusServiceUnavailable
+					break
+				}
+			}
+
+			body, err := json.Marshal(statuses)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(code)
+			w.Write(body)
+
+		default:
+			http.NotFound(w, r)
+		}
+	})
+}
+
+// Ping the server until it replies, retry [Config].FailFastRetries times with exponential backoff.
+func (cfg *Config) waitReady(client redis.UniversalClient) error {
+	backoff := cfg.FailFastBackoff
+	for attempt := 0; ; attempt++ {
+		err := client.Ping(context.Background()).Err()
+		if err == nil {
+			return nil
+		}
+		if attempt >= cfg.FailFastRetries {
+			return fmt.Errorf("server is unreachable after %d attempt(s): %w", attempt+1, err)
+		}
+
+		goutils.Warnf("redis[%s]: server is unreachable, retry in %s: %s", cfg.ConnectionName, backoff, err)
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
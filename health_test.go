@@ -0,0 +1,156 @@
+package goredis_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/hecigo/goredis"
+	"github.com/hecigo/goutils"
+	. "gopkg.in/check.v1"
+)
+
+type HealthSuite struct{}
+
+var _ = Suite(&HealthSuite{})
+
+func (s *HealthSuite) SetUpSuite(c *C) {
+	fmt.Println("SetUpSuite > HealthSuite")
+	goutils.QuickLoad()
+}
+
+func (s *HealthSuite) TearDownTest(c *C) {
+	goredis.Close("health", "health_down")
+}
+
+// Test pinging an opened connection
+func (s *HealthSuite) TestPing(c *C) {
+	c.Assert(goredis.Open("health"), IsNil)
+	c.Assert(goredis.Ping(context.Background(), "health"), IsNil)
+
+	err := goredis.Ping(context.Background(), "missing")
+	c.Assert(err, Equals, goredis.ErrConnectionNotOpened{Name: "missing"})
+}
+
+// Test the statistics of the connection pool
+func (s *HealthSuite) TestPoolStats(c *C) {
+	c.Assert(goredis.Open("health"), IsNil)
+	for i := 0; i < 3; i++ {
+		c.Assert(goredis.Ping(context.Background(), "health"), IsNil)
+	}
+
+	stats, err := goredis.PoolStats("health")
+	c.Assert(err, IsNil)
+	c.Assert(stats.TotalConns > 0, Equals, true)
+	c.Assert(stats.Hits+stats.Misses >= 3, Equals, true)
+
+	_, err = goredis.PoolStats("missing")
+	c.Assert(err, Equals, goredis.ErrConnectionNotOpened{Name: "missing"})
+}
+
+// Test the health of healthy and unreachable connections
+func (s *HealthSuite) TestHealthCheck(c *C) {
+	c.Assert(goredis.Open("health"), IsNil)
+	c.Assert(goredis.OpenWithConfig(&goredis.Config{
+		ConnectionName: "health_down",
+		Addresses:      []string{"localhost:1"},
+		KeyPrefix:      "test-health",
+		MaxRetries:     -1,
+	}), IsNil)
+
+	statuses := healthStatuses(goredis.HealthCheck(context.Background()))
+
+	up := statuses["health"]
+	c.Assert(up.Healthy, Equals, true)
+	c.Assert(up.Error, Equals, "")
+	c.Assert(up.Latency > 0, Equals, true)
+	// the role is empty if the server does not support `INFO replication`
+	c.Assert(up.Role == "master" || up.Role == "", Equals, true)
+	c.Assert(up.PoolStats, NotNil)
+
+	down := statuses["health_down"]
+	c.Assert(down.Healthy, Equals, false)
+	c.Assert(down.Error, Not(Equals), "")
+}
+
+// Test the role is not detected in cluster mode, even if the mode is guessed from the addresses
+func (s *HealthSuite) TestClusterRole(c *C) {
+	log := &hookLog{}
+	c.Assert(goredis.OpenWithConfig(&goredis.Config{
+		ConnectionName: "health",
+		Addresses:      []string{"localhost:6379", "localhost:6380"},
+		KeyPrefix:      "test-health",
+		Hooks:          []redis.Hook{&recordHook{name: "health", log: log}},
+	}), IsNil)
+
+	status := healthStatuses(goredis.HealthCheck(context.Background()))["health"]
+	c.Assert(status.Role, Equals, "")
+	for _, entry := range log.entries() {
+		c.Assert(entry, Not(Equals), "health:before:info")
+	}
+}
+
+// Test the liveness and readiness probes
+func (s *HealthSuite) TestHealthHandler(c *C) {
+	c.Assert(goredis.Open("health"), IsNil)
+	c.Assert(goredis.OpenWithConfig(&goredis.Config{
+		ConnectionName: "health_down",
+		Addresses:      []string{"localhost:1"},
+		KeyPrefix:      "test-health",
+		MaxRetries:     -1,
+	}), IsNil)
+
+	handler := goredis.HealthHandler()
+	serve := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w
+	}
+
+	c.Assert(serve("/health/livez").Code, Equals, http.StatusOK)
+	c.Assert(serve("/health/unknown").Code, Equals, http.StatusNotFound)
+
+	w := serve("/health/readyz")
+	c.Assert(w.Code, Equals, http.StatusServiceUnavailable)
+	c.Assert(w.Header().Get("Content-Type"), Equals, "application/json")
+	c.Assert(w.Body.String(), Matches, `.*"name":"health_down".*`)
+
+	c.Assert(goredis.Close("health_down"), IsNil)
+	c.Assert(serve("/health/readyz").Code, Equals, http.StatusOK)
+}
+
+// Test a fail-fast connection is not registered if the server is unreachable
+func (s *HealthSuite) TestFailFast(c *C) {
+	start := time.Now()
+	err := goredis.OpenWithConfig(&goredis.Config{
+		ConnectionName:  "health_down",
+		Addresses:       []string{"localhost:1"},
+		KeyPrefix:       "test-health",
+		MaxRetries:      -1,
+		FailFast:        true,
+		FailFastRetries: 2,
+		FailFastBackoff: 10 * time.Millisecond,
+	})
+	c.Assert(err, ErrorMatches, ".*unreachable after 3 attempt.*")
+	c.Assert(time.Since(start) >= 30*time.Millisecond, Equals, true)
+	c.Assert(goredis.Ping(context.Background(), "health_down"), Equals, goredis.ErrConnectionNotOpened{Name: "health_down"})
+
+	err = goredis.OpenWithConfig(&goredis.Config{
+		ConnectionName: "health",
+		KeyPrefix:      "test-health",
+		FailFast:       true,
+	})
+	c.Assert(err, IsNil)
+	c.Assert(goredis.Ping(context.Background(), "health"), IsNil)
+}
+
+func healthStatuses(statuses []goredis.HealthStatus) map[string]goredis.HealthStatus {
+	m := make(map[string]goredis.HealthStatus, len(statuses))
+	for _, status := range statuses {
+		m[status.Name] = status
+	}
+	return m
+}
@@ -0,0 +1,46 @@
+package goredis
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// Register a hook to the connection with name, such as an audit log, a fault injection or custom metrics.
+// The empty name means the default connection.
+//
+// The hook is applied every time the connection is opened, so it is kept when the connection is reopened.
+// Register the hooks before [Open], an opened connection picks up the new hooks when it is reopened.
+//
+// The hooks of a connection are applied in this order, [redis.Hook].BeforeProcess runs in the same order
+// and [redis.Hook].AfterProcess in the reverse order:
+//  1. the built-in hooks, tracing and metrics
+//  2. [Config].Hooks, in the order of the slice
+//  3. the registered hooks, in the order of registration
+func RegisterHook(name string, hook redis.Hook) error {
+	if hook == nil {
+		return errors.New("redis: hook is nil")
+	}
+	if name == "" {
+		name = "default"
+	}
+
+	reg.addHook(name, hook)
+	return nil
+}
+
+// Returns the user hooks of the connection, [Config].Hooks followed by the registered hooks.
+func (cfg *Config) userHooks() []redis.Hook {
+	hooks := append([]redis.Hook(nil), cfg.Hooks...)
+	return append(hooks, reg.hookList(cfg.ConnectionName)...)
+}
+
+// Returns the type names of the hooks to print them.
+func hookNames(hooks []redis.Hook) []string {
+	names := make([]string, len(hooks))
+	for i, hook := range hooks {
+		names[i] = fmt.Sprintf("%T", hook)
+	}
+	return names
+}
@@ -0,0 +1,105 @@
+package goredis_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/hecigo/goredis"
+	"github.com/hecigo/goutils"
+	. "gopkg.in/check.v1"
+)
+
+type HookSuite struct{}
+
+var _ = Suite(&HookSuite{})
+
+func (s *HookSuite) SetUpSuite(c *C) {
+	fmt.Println("SetUpSuite > HookSuite")
+	goutils.QuickLoad()
+}
+
+func (s *HookSuite) TearDownSuite(c *C) {
+	goredis.Close("hook", "hook2")
+}
+
+// Test the hooks of the configuration and the registered hooks run in order
+func (s *HookSuite) TestOrder(c *C) {
+	log := &hookLog{}
+	c.Assert(goredis.RegisterHook("hook", &recordHook{name: "registered", log: log}), IsNil)
+	c.Assert(goredis.OpenWithConfig(&goredis.Config{
+		ConnectionName: "hook",
+		KeyPrefix:      "test-hook",
+		Hooks:          []redis.Hook{&recordHook{name: "first", log: log}, &recordHook{name: "second", log: log}},
+	}), IsNil)
+
+	ctx := context.WithValue(context.Background(), goutils.CtxKey_ConnName, "hook")
+	c.Assert(goredis.Set(ctx, "test_hook", "value"), IsNil)
+	c.Assert(log.entries(), DeepEquals, []string{
+		"first:before:set", "second:before:set", "registered:before:set",
+		"registered:after:set", "second:after:set", "first:after:set",
+	})
+
+	c.Assert(goredis.OpenWithConfig(&goredis.Config{ConnectionName: "hook", Hooks: []redis.Hook{nil}}), ErrorMatches, ".*hook 0 is nil.*")
+	c.Assert(goredis.RegisterHook("hook", nil), NotNil)
+}
+
+// Test the registered hooks are kept when the connection is reopened
+func (s *HookSuite) TestReopen(c *C) {
+	log := &hookLog{}
+	c.Assert(goredis.RegisterHook("hook2", &recordHook{name: "audit", log: log}), IsNil)
+
+	os.Setenv("REDISHOOK2_KEY_PREFIX", "test-hook")
+	defer os.Unsetenv("REDISHOOK2_KEY_PREFIX")
+	c.Assert(goredis.Open("hook2"), IsNil)
+	c.Assert(goredis.Open("hook2"), IsNil)
+
+	ctx := context.WithValue(context.Background(), goutils.CtxKey_ConnName, "hook2")
+	_, err := goredis.Client(ctx).Ping(ctx).Result()
+	c.Assert(err, IsNil)
+	c.Assert(log.entries(), DeepEquals, []string{"audit:before:ping", "audit:after:ping"})
+}
+
+// The entries recorded by the hooks, safe for concurrent use.
+type hookLog struct {
+	mu   sync.Mutex
+	list []string
+}
+
+func (l *hookLog) add(entry string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.list = append(l.list, entry)
+}
+
+func (l *hookLog) entries() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]string(nil), l.list...)
+}
+
+// A hook to record the order of the hooks.
+type recordHook struct {
+	name string
+	log  *hookLog
+}
+
+func (h *recordHook) BeforeProcess(ctx context.Context, cmd redis.Cmder) (context.Context, error) {
+	h.log.add(h.name + ":before:" + cmd.Name())
+	return ctx, nil
+}
+
+func (h *recordHook) AfterProcess(ctx context.Context, cmd redis.Cmder) error {
+	h.log.add(h.name + ":after:" + cmd.Name())
+	return nil
+}
+
+func (h *recordHook) BeforeProcessPipeline(ctx context.Context, cmds []redis.Cmder) (context.Context, error) {
+	return ctx, nil
+}
+
+func (h *recordHook) AfterProcessPipeline(ctx context.Context, cmds []redis.Cmder) error {
+	return nil
+}
@@ -0,0 +1,100 @@
+package goredis
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Set by [WithRawKeys] to use the keys of a call as they are, without the key prefix.
+const CtxKey_RawKeys ctxKeyType_Redis = "redis_raw_keys"
+
+// Builds the Redis keys of a call as `<prefix><separator><key>`, see [GetKeyBuilder].
+// It never modifies the keys passed to it.
+type KeyBuilder struct {
+	// The key prefix of the call, [Config].KeyPrefix or [WithKeyPrefix], followed by [WithKeySegments].
+	Prefix string
+
+	// The separator between the prefix and the key, see [Config].KeySeparator.
+	Separator string
+
+	// Wrap the prefix in a cluster hash tag `{prefix}`, see [Config].KeyHashTag.
+	HashTag bool
+
+	// Use the keys as they are, without the prefix, see [WithRawKeys].
+	Raw bool
+}
+
+// Returns a copy of ctx to use the keys of the call as they are, e.g. to read a key written by another application.
+// See [CtxKey_RawKeys].
+func WithRawKeys(ctx context.Context) context.Context {
+	return context.WithValue(ctx, CtxKey_RawKeys, true)
+}
+
+// Returns the key builder of the call, following the configuration of the connection
+// and the key prefix, segments and raw keys set in context.
+// It is useful to build the same keys as [Get] and [Set] for the commands of [Client].
+func GetKeyBuilder(ctx context.Context) (KeyBuilder, error) {
+	cfg, err := GetConfigE(ctx)
+	if err != nil {
+		return KeyBuilder{}, err
+	}
+	return newKeyBuilder(ctx, cfg)
+}
+
+// Returns the key builder of the call on the connection with cfg.
+func newKeyBuilder(ctx context.Context, cfg *Config) (KeyBuilder, error) {
+	prefix, _, err := ctxKeyScope(ctx, cfg.KeyPrefix, cfg.KeySeparator)
+	if err != nil {
+		return KeyBuilder{}, err
+	}
+
+	var raw bool
+	switch v := ctx.Value(CtxKey_RawKeys).(type) {
+	case nil:
+	case bool:
+		raw = v
+	default:
+		return KeyBuilder{}, fmt.Errorf("redis: %s must be a bool, got %T", CtxKey_RawKeys, v)
+	}
+
+	return KeyBuilder{
+		Prefix:    prefix,
+		Separator: cfg.KeySeparator,
+		HashTag:   cfg.KeyHashTag,
+		Raw:       raw,
+	}, nil
+}
+
+// Returns the key with the prefix.
+func (b KeyBuilder) Key(key string) string {
+	if b.Raw {
+		return key
+	}
+	return b.head() + key
+}
+
+// Returns a new slice of the keys with the prefix.
+func (b KeyBuilder) Keys(keys ...string) []string {
+	r := make([]string, len(keys))
+	for i, k := range keys {
+		r[i] = b.Key(k)
+	}
+	return r
+}
+
+// Returns the key without the prefix, the reverse of [KeyBuilder.Key].
+func (b KeyBuilder) Strip(key string) string {
+	if b.Raw {
+		return key
+	}
+	return strings.TrimPrefix(key, b.head())
+}
+
+// Returns the part before the key, the prefix in the hash tag if any and the separator.
+func (b KeyBuilder) head() string {
+	if b.HashTag {
+		return "{" + b.Prefix + "}" + b.Separator
+	}
+	return b.Prefix + b.Separator
+}
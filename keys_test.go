@@ -0,0 +1,69 @@
+package goredis_test
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hecigo/goredis"
+	"github.com/hecigo/goutils"
+	. "gopkg.in/check.v1"
+)
+
+type KeySuite struct{}
+
+var _ = Suite(&KeySuite{})
+
+func (s *KeySuite) SetUpSuite(c *C) {
+	fmt.Println("SetUpSuite > KeySuite")
+	goutils.QuickLoad()
+}
+
+func (s *KeySuite) TearDownTest(c *C) {
+	goredis.Close("keys")
+}
+
+// Test the keys are built with the separator and the hash tag, and the caller's keys are not modified
+func (s *KeySuite) TestBuilder(c *C) {
+	c.Assert(goredis.OpenWithConfig(&goredis.Config{ConnectionName: "keys", KeyPrefix: "test-keys", KeySeparator: ":", KeyHashTag: true}), IsNil)
+	ctx := goredis.WithConnection(context.Background(), "keys")
+
+	c.Assert(goredis.MSet(ctx, map[string]interface{}{"test_keys_1": "v1", "test_keys_2": "v2"}), IsNil)
+	c.Assert(goredis.Client(ctx).Get(ctx, "{test-keys}:test_keys_1").Val(), Equals, "v1")
+
+	keys := []string{"test_keys_1", "test_keys_2"}
+	m, err := goredis.Get[string](ctx, keys...)
+	c.Assert(err, IsNil)
+	c.Assert(*m.(map[string]*string)["test_keys_2"], Equals, "v2")
+	c.Assert(keys, DeepEquals, []string{"test_keys_1", "test_keys_2"})
+
+	c.Assert(goredis.GetRankingBoard(goredis.WithKeySegments(ctx, "tenant-1"), "test_keys_zset").Id, Equals, "{test-keys:tenant-1}:test_keys_zset")
+
+	kb, err := goredis.GetKeyBuilder(ctx)
+	c.Assert(err, IsNil)
+	c.Assert(kb.Keys(keys...), DeepEquals, []string{"{test-keys}:test_keys_1", "{test-keys}:test_keys_2"})
+	c.Assert(kb.Strip("{test-keys}:test_keys_1"), Equals, "test_keys_1")
+}
+
+// Test the raw keys are used as they are
+func (s *KeySuite) TestRawKeys(c *C) {
+	c.Assert(goredis.OpenWithConfig(&goredis.Config{ConnectionName: "keys", KeyPrefix: "test-keys"}), IsNil)
+	ctx := goredis.WithConnection(context.Background(), "keys")
+
+	raw := goredis.WithRawKeys(ctx)
+	c.Assert(goredis.Set(raw, "test-other.test_keys_raw", "raw"), IsNil)
+	c.Assert(goredis.Client(ctx).Get(ctx, "test-other.test_keys_raw").Val(), Equals, "raw")
+
+	v, err := goredis.Get[string](raw, "test-other.test_keys_raw")
+	c.Assert(err, IsNil)
+	c.Assert(v, Equals, "raw")
+	c.Assert(goredis.GetRankingBoard(raw, "test_keys_zset").Id, Equals, "test_keys_zset")
+
+	_, err = goredis.Get[string](context.WithValue(ctx, goredis.CtxKey_RawKeys, "yes"), "test_keys_raw")
+	c.Assert(err, ErrorMatches, "redis: redis_raw_keys must be a bool, got string")
+}
+
+// Test the prefix of a hash tag must not contain braces
+func (s *KeySuite) TestValidation(c *C) {
+	err := goredis.OpenWithConfig(&goredis.Config{ConnectionName: "keys", KeyPrefix: "{test-keys}", KeyHashTag: true})
+	c.Assert(err, ErrorMatches, ".*key prefix must not contain braces with key hash tag.*")
+}
@@ -3,7 +3,34 @@ package goredis_test
 import (
 	"testing"
 
+	"github.com/hecigo/goredis/goredistest"
 	. "gopkg.in/check.v1"
 )
 
-func Test(t *testing.T) { TestingT(t) }
+func Test(t *testing.T) {
+	runOffline(t)
+	TestingT(t)
+}
+
+// The master and the replica served in-process at localhost:6379 and localhost:6381. The replica does not replicate
+// the master, so the tests seed it with other values to tell which server a read comes from.
+var offlineMaster, offlineReplica *goredistest.Server
+
+// The suites use the Redis servers at localhost:6379 and localhost:6380, and a replica of the first one at localhost:6381,
+// which is the default address of the connections. They are always served in-process,
+// and the tests fail if another server already listens at one of the ports, rather than writing to it.
+func runOffline(t *testing.T) {
+	for _, addr := range []string{"localhost:6379", "localhost:6380", "localhost:6381"} {
+		srv, err := goredistest.Run(addr)
+		if err != nil {
+			t.Fatalf("stop the Redis server listening at %s to run the tests: %s", addr, err)
+		}
+		t.Cleanup(srv.Close)
+		switch addr {
+		case "localhost:6379":
+			offlineMaster = srv
+		case "localhost:6381":
+			offlineReplica = srv
+		}
+	}
+}
@@ -0,0 +1,229 @@
+package goredis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/hecigo/goutils"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// The Prometheus collector of all connections, created by [Metrics].
+type metricsCollector struct {
+	commands       *prometheus.CounterVec
+	duration       *prometheus.HistogramVec
+	errors         *prometheus.CounterVec
+	pipelineSize   *prometheus.HistogramVec
+	failovers      *prometheus.CounterVec
+	shadow         *prometheus.CounterVec
+	poolHits       *prometheus.Desc
+	poolMisses     *prometheus.Desc
+	poolTimeouts   *prometheus.Desc
+	poolTotalConns *prometheus.Desc
+	poolIdleConns  *prometheus.Desc
+	poolStaleConns *prometheus.Desc
+}
+
+var (
+	metricsOnce sync.Once
+	metrics     *metricsCollector
+)
+
+// Returns the Prometheus collector of all connections, register it on your own registry:
+//
+//	prometheus.MustRegister(goredis.Metrics())
+//
+// The commands are only measured on the connections opened with env `REDIS_METRICS_ENABLE=true`,
+// or `REDIS<name>_METRICS_ENABLE=true` for a single connection. The metric names are prefixed by
+// env `REDIS_METRICS_NAMESPACE`, default `goredis`. The pool statistics are collected from every opened connection.
+//
+//   - `<namespace>_commands_total{connection, command}`: the number of processed commands
+//   - `<namespace>_command_duration_seconds{connection, command}`: the latency of commands, `pipeline` for a whole pipeline
+//   - `<namespace>_errors_total{connection, command, class}`: the number of failed commands by error class,
+//     one of `timeout`, `canceled`, `pool_timeout`, `closed`, `network`, `server` and `other`
+//   - `<namespace>_pipeline_size{connection}`: the number of commands per pipeline
+//   - `<namespace>_failovers_total{connection, fallback, kind}`: the number of calls failed over to
+//     [Config].FallbackConnection, kind is `read` or `write`. It is counted on every connection.
+//   - `<namespace>_shadow_total{connection, shadow, result}`: the calls mirrored to [Config].ShadowConnection by result,
+//     one of `mirrored`, `matched`, `mismatched`, `error` and `dropped`. It is counted on every connection.
+//   - `<namespace>_pool_*{connection}`: the statistics of the connection pool, see [PoolStats]
+func Metrics() prometheus.Collector {
+	metricsOnce.Do(func() {
+		metrics = newMetricsCollector(goutils.Env("REDIS_METRICS_NAMESPACE", "goredis"))
+	})
+	return metrics
+}
+
+func newMetricsCollector(namespace string) *metricsCollector {
+	poolDesc := func(name, help string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(namespace, "pool", name), help, []string{"connection"}, nil)
+	}
+
+	return &metricsCollector{
+		commands: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "commands_total",
+			Help:      "The number of processed Redis commands.",
+		}, []string{"connection", "command"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "command_duration_seconds",
+			Help:      "The latency of Redis commands, `pipeline` for a whole pipeline.",
+			Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+		}, []string{"connection", "command"}),
+		errors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "errors_total",
+			Help:      "The number of failed Redis commands by error class.",
+		}, []string{"connection", "command", "class"}),
+		pipelineSize: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "pipeline_size",
+			Help:      "The number of commands per Redis pipeline.",
+			Buckets:   prometheus.ExponentialBuckets(1, 2, 10),
+		}, []string{"connection"}),
+		failovers: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "failovers_total",
+			Help:      "The number of calls failed over to the fallback connection.",
+		}, []string{"connection", "fallback", "kind"}),
+		shadow: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "shadow_total",
+			Help:      "The number of calls mirrored to the shadow connection by result.",
+		}, []string{"connection", "shadow", "result"}),
+		poolHits:       poolDesc("hits_total", "The number of times a free connection was found in the pool."),
+		poolMisses:     poolDesc("misses_total", "The number of times a free connection was not found in the pool."),
+		poolTimeouts:   poolDesc("timeouts_total", "The number of times a wait for a connection timed out."),
+		poolTotalConns: poolDesc("total_conns", "The number of connections in the pool."),
+		poolIdleConns:  poolDesc("idle_conns", "The number of idle connections in the pool."),
+		poolStaleConns: poolDesc("stale_conns_total", "The number of stale connections removed from the pool."),
+	}
+}
+
+// Implements [prometheus.Collector].
+func (m *metricsCollector) Describe(ch chan<- *prometheus.Desc) {
+	m.commands.Describe(ch)
+	m.duration.Describe(ch)
+	m.errors.Describe(ch)
+	m.pipelineSize.Describe(ch)
+	m.failovers.Describe(ch)
+	m.shadow.Describe(ch)
+	ch <- m.poolHits
+	ch <- m.poolMisses
+	ch <- m.poolTimeouts
+	ch <- m.poolTotalConns
+	ch <- m.poolIdleConns
+	ch <- m.poolStaleConns
+}
+
+// Implements [prometheus.Collector].
+func (m *metricsCollector) Collect(ch chan<- prometheus.Metric) {
+	m.commands.Collect(ch)
+	m.duration.Collect(ch)
+	m.errors.Collect(ch)
+	m.pipelineSize.Collect(ch)
+	m.failovers.Collect(ch)
+	m.shadow.Collect(ch)
+
+	for _, conn := range reg.connList() {
+		name := conn.config.ConnectionName
+		stats := conn.client.PoolStats()
+		ch <- prometheus.MustNewConstMetric(m.poolHits, prometheus.CounterValue, float64(stats.Hits), name)
+		ch <- prometheus.MustNewConstMetric(m.poolMisses, prometheus.CounterValue, float64(stats.Misses), name)
+		ch <- prometheus.MustNewConstMetric(m.poolTimeouts, prometheus.CounterValue, float64(stats.Timeouts), name)
+		ch <- prometheus.MustNewConstMetric(m.poolTotalConns, prometheus.GaugeValue, float64(stats.TotalConns), name)
+		ch <- prometheus.MustNewConstMetric(m.poolIdleConns, prometheus.GaugeValue, float64(stats.IdleConns), name)
+		ch <- prometheus.MustNewConstMetric(m.poolStaleConns, prometheus.CounterValue, float64(stats.StaleConns), name)
+	}
+}
+
+// Returns true if the metrics are enabled for the connection by env.
+func metricsEnabled(connName string) bool {
+	enabled := goutils.Env("REDIS_METRICS_ENABLE", false)
+	return goutils.Env(fmt.Sprintf("REDIS%s_METRICS_ENABLE", envName(connName)), enabled)
+}
+
+type ctxKeyType_Metrics struct{}
+
+// The [redis.Hook] to measure the commands of a connection.
+type metricsHook struct {
+	connName string
+	metrics  *metricsCollector
+}
+
+func newMetricsHook(connName string) *metricsHook {
+	Metrics()
+	return &metricsHook{connName: connName, metrics: metrics}
+}
+
+func (h *metricsHook) BeforeProcess(ctx context.Context, cmd redis.Cmder) (context.Context, error) {
+	return context.WithValue(ctx, ctxKeyType_Metrics{}, time.Now()), nil
+}
+
+func (h *metricsHook) AfterProcess(ctx context.Context, cmd redis.Cmder) error {
+	if start, ok := ctx.Value(ctxKeyType_Metrics{}).(time.Time); ok {
+		h.metrics.duration.WithLabelValues(h.connName, cmd.Name()).Observe(time.Since(start).Seconds())
+	}
+	h.observe(cmd)
+	return nil
+}
+
+func (h *metricsHook) BeforeProcessPipeline(ctx context.Context, cmds []redis.Cmder) (context.Context, error) {
+	return context.WithValue(ctx, ctxKeyType_Metrics{}, time.Now()), nil
+}
+
+func (h *metricsHook) AfterProcessPipeline(ctx context.Context, cmds []redis.Cmder) error {
+	if start, ok := ctx.Value(ctxKeyType_Metrics{}).(time.Time); ok {
+		h.metrics.duration.WithLabelValues(h.connName, "pipeline").Observe(time.Since(start).Seconds())
+	}
+	h.metrics.pipelineSize.WithLabelValues(h.connName).Observe(float64(len(cmds)))
+	for _, cmd := range cmds {
+		h.observe(cmd)
+	}
+	return nil
+}
+
+// Count the command and its error.
+func (h *metricsHook) observe(cmd redis.Cmder) {
+	h.metrics.commands.WithLabelValues(h.connName, cmd.Name()).Inc()
+	if class := errorClass(cmd.Err()); class != "" {
+		h.metrics.errors.WithLabelValues(h.connName, cmd.Name(), class).Inc()
+	}
+}
+
+// Returns the class of the error to keep the cardinality of the metrics low, or empty if it is not an error.
+// [redis.Nil] is not an error, it means the key does not exist.
+func errorClass(err error) string {
+	var netErr net.Error
+	var redisErr redis.Error
+	switch {
+	case err == nil || err == redis.Nil:
+		return ""
+	case errors.Is(err, context.DeadlineExceeded):
+		return "timeout"
+	case errors.Is(err, context.Canceled):
+		return "canceled"
+	case err.Error() == "redis: connection pool timeout":
+		return "pool_timeout"
+	case errors.Is(err, redis.ErrClosed):
+		return "closed"
+	case errors.As(err, &netErr):
+		if netErr.Timeout() {
+			return "timeout"
+		}
+		return "network"
+	case errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF):
+		return "network"
+	case errors.As(err, &redisErr):
+		return "server"
+	default:
+		return "other"
+	}
+}
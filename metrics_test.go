@@ -0,0 +1,102 @@
+package goredis_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/hecigo/goredis"
+	"github.com/hecigo/goutils"
+	"github.com/prometheus/client_golang/prometheus"
+	. "gopkg.in/check.v1"
+)
+
+type MetricsSuite struct {
+	registry *prometheus.Registry
+}
+
+var _ = Suite(&MetricsSuite{})
+
+func (s *MetricsSuite) SetUpSuite(c *C) {
+	fmt.Println("SetUpSuite > MetricsSuite")
+	goutils.QuickLoad()
+
+	os.Setenv("REDISMETRICS_METRICS_ENABLE", "true")
+	os.Setenv("REDISMETRICS_KEY_PREFIX", "test-metrics")
+	c.Assert(goredis.Open("metrics"), IsNil)
+
+	s.registry = prometheus.NewRegistry()
+	c.Assert(s.registry.Register(goredis.Metrics()), IsNil)
+}
+
+func (s *MetricsSuite) TearDownSuite(c *C) {
+	goredis.Close("metrics")
+	os.Unsetenv("REDISMETRICS_METRICS_ENABLE")
+	os.Unsetenv("REDISMETRICS_KEY_PREFIX")
+}
+
+// Test the commands, errors and pipelines are measured
+func (s *MetricsSuite) TestCommands(c *C) {
+	ctx := context.WithValue(context.Background(), goutils.CtxKey_ConnName, "metrics")
+	before := s.gather(c)
+
+	c.Assert(goredis.Set(ctx, "test_metrics", "value"), IsNil)
+	_, err := goredis.Get[string](ctx, "test_metrics")
+	c.Assert(err, IsNil)
+	_, err = goredis.Get[string](ctx, "test_metrics", "test_metrics_missing")
+	c.Assert(err, IsNil)
+
+	// a server error, the key holds a string
+	_, err = goredis.Client(ctx).LPush(ctx, "test-metrics.test_metrics", "value").Result()
+	c.Assert(err, NotNil)
+
+	// a pipeline of 3 commands
+	_, err = goredis.Client(ctx).Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Get(ctx, "test-metrics.test_metrics")
+		pipe.Get(ctx, "test-metrics.test_metrics")
+		pipe.Get(ctx, "test-metrics.test_metrics")
+		return nil
+	})
+	c.Assert(err, IsNil)
+
+	after := s.gather(c)
+	c.Assert(after[`goredis_commands_total{command="set",connection="metrics"}`]-before[`goredis_commands_total{command="set",connection="metrics"}`], Equals, 1.0)
+	c.Assert(after[`goredis_commands_total{command="get",connection="metrics"}`]-before[`goredis_commands_total{command="get",connection="metrics"}`], Equals, 4.0)
+	c.Assert(after[`goredis_commands_total{command="mget",connection="metrics"}`]-before[`goredis_commands_total{command="mget",connection="metrics"}`], Equals, 1.0)
+	c.Assert(after[`goredis_errors_total{class="server",command="lpush",connection="metrics"}`]-before[`goredis_errors_total{class="server",command="lpush",connection="metrics"}`], Equals, 1.0)
+	c.Assert(after[`goredis_command_duration_seconds_count{command="set",connection="metrics"}`] > 0, Equals, true)
+	c.Assert(after[`goredis_command_duration_seconds_count{command="pipeline",connection="metrics"}`] > 0, Equals, true)
+	c.Assert(after[`goredis_pipeline_size_sum{connection="metrics"}`]-before[`goredis_pipeline_size_sum{connection="metrics"}`], Equals, 3.0)
+	c.Assert(after[`goredis_pool_total_conns{connection="metrics"}`] > 0, Equals, true)
+}
+
+// Gather the metrics as a map of `name{labels}` => value, histograms are flattened to `_count` and `_sum`
+func (s *MetricsSuite) gather(c *C) map[string]float64 {
+	families, err := s.registry.Gather()
+	c.Assert(err, IsNil)
+
+	result := make(map[string]float64)
+	for _, family := range families {
+		for _, m := range family.GetMetric() {
+			labels := ""
+			for i, l := range m.GetLabel() {
+				if i > 0 {
+					labels += ","
+				}
+				labels += fmt.Sprintf(`%s="%s"`, l.GetName(), l.GetValue())
+			}
+
+			switch {
+			case m.GetCounter() != nil:
+				result[fmt.Sprintf("%s{%s}", family.GetName(), labels)] = m.GetCounter().GetValue()
+			case m.GetGauge() != nil:
+				result[fmt.Sprintf("%s{%s}", family.GetName(), labels)] = m.GetGauge().GetValue()
+			case m.GetHistogram() != nil:
+				result[fmt.Sprintf("%s_count{%s}", family.GetName(), labels)] = float64(m.GetHistogram().GetSampleCount())
+				result[fmt.Sprintf("%s_sum{%s}", family.GetName(), labels)] = m.GetHistogram().GetSampleSum()
+			}
+		}
+	}
+	return result
+}
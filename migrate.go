@@ -0,0 +1,452 @@
+package goredis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/hecigo/goutils"
+)
+
+// The options of [MigratePrefix].
+type MigrateOptions struct {
+	// Copy the keys and keep the old ones, instead of renaming them.
+	Copy bool
+
+	// Overwrite the keys which already exist under the new prefix. Otherwise they are skipped.
+	Replace bool
+
+	// Only scan the keys and count what would be migrated, without writing anything.
+	DryRun bool
+
+	// The maximum number of keys migrated per second.
+	// Default: 0, which means no limit.
+	Rate int
+
+	// The COUNT hint of SCAN.
+	// Default: 100
+	ScanCount int64
+
+	// Called after every batch of scanned keys with the stats so far. It is never called concurrently.
+	Progress func(stats MigrateStats)
+}
+
+// The result of [MigratePrefix].
+type MigrateStats struct {
+	Scanned  int64 // the keys found under the old prefix
+	Migrated int64 // the keys renamed or copied, or which would be in dry-run mode
+	Skipped  int64 // the keys which already exist under the new prefix, or expired during the migration
+}
+
+// Move the keys from the prefix `from` to the prefix `to` on the connection of ctx, e.g. after renaming the application,
+// since [Config].KeyPrefix defaults to the application name. The prefixes are joined to the keys
+// with [Config].KeySeparator and [Config].KeyHashTag of the connection, as [KeyBuilder] does.
+//
+// The keys are found by SCAN, then renamed, or copied if opts.Copy is set. Their TTLs are kept.
+// In cluster and ring modes, every master is scanned and the keys are moved by DUMP and RESTORE,
+// since the new key may belong to another slot or shard. If the servers do not know DUMP,
+// the strings, hashes, lists, sets and sorted sets are read and written again instead.
+//
+// The migration is not atomic: the keys written under the old prefix meanwhile may be missed, run it again to move them.
+// On error, it stops and returns the stats so far.
+func MigratePrefix(ctx context.Context, from string, to string, opts *MigrateOptions) (MigrateStats, error) {
+	conn, err := getConn(ctx)
+	if err != nil {
+		return MigrateStats{}, err
+	}
+
+	m, err := newMigration(conn, from, to, opts)
+	if err != nil {
+		return MigrateStats{}, fmt.Errorf("redis[%s]: %w", conn.config.ConnectionName, err)
+	}
+
+	switch client := conn.client.(type) {
+	case *redis.ClusterClient:
+		err = client.ForEachMaster(ctx, func(ctx context.Context, node *redis.Client) error {
+			return m.scan(ctx, node, client)
+		})
+	case *redis.Ring:
+		err = client.ForEachShard(ctx, func(ctx context.Context, shard *redis.Client) error {
+			return m.scan(ctx, shard, client)
+		})
+	default:
+		err = m.scan(ctx, client, nil)
+	}
+
+	stats := m.snapshot()
+	if err != nil {
+		return stats, fmt.Errorf("redis[%s]: migrate `%s` to `%s`: %w", conn.config.ConnectionName, from, to, err)
+	}
+
+	verb := "migrated"
+	if m.opts.DryRun {
+		verb = "would migrate"
+	}
+	goutils.Printf("redis[%s]: %s %d keys from `%s` to `%s`, %d skipped",
+		conn.config.ConnectionName, verb, stats.Migrated, from, to, stats.Skipped)
+	return stats, nil
+}
+
+// A running [MigratePrefix]. The masters of a cluster are scanned concurrently, so the stats are guarded by mu.
+type migration struct {
+	from    KeyBuilder
+	to      KeyBuilder
+	opts    MigrateOptions
+	limiter *rateLimiter
+
+	mu    sync.Mutex
+	stats MigrateStats
+
+	// The new keys which match the old prefix too, e.g. `app` to `app.v2`, or `app.v2.v2.x` to `app.v2.x`,
+	// so that they are not moved again if SCAN returns them.
+	moved map[string]struct{}
+}
+
+func newMigration(conn *connection, from string, to string, opts *MigrateOptions) (*migration, error) {
+	if from == "" || to == "" {
+		return nil, errors.New("the prefixes must not be empty")
+	}
+	if from == to {
+		return nil, errors.New("the prefixes must be different")
+	}
+
+	m := &migration{
+		from:  KeyBuilder{Prefix: from, Separator: conn.config.KeySeparator, HashTag: conn.config.KeyHashTag},
+		to:    KeyBuilder{Prefix: to, Separator: conn.config.KeySeparator, HashTag: conn.config.KeyHashTag},
+		moved: make(map[string]struct{}),
+	}
+	if opts != nil {
+		m.opts = *opts
+	}
+	if m.opts.Rate < 0 {
+		return nil, errors.New("the rate must not be negative")
+	}
+	if m.opts.ScanCount <= 0 {
+		m.opts.ScanCount = 100
+	}
+	if m.opts.Rate > 0 {
+		m.limiter = &rateLimiter{interval: time.Second / time.Duration(m.opts.Rate)}
+	}
+	return m, nil
+}
+
+// Scan the keys of the old prefix on node, then move them. dumper is the client to RESTORE the keys
+// in cluster and ring modes, or nil to rename or copy them on node.
+func (m *migration) scan(ctx context.Context, node redis.Cmdable, dumper redis.Cmdable) error {
+	head := m.from.Key("")
+	pattern := escapeGlob(head) + "*"
+
+	var cursor uint64
+	for {
+		keys, next, err := node.Scan(ctx, cursor, pattern, m.opts.ScanCount).Result()
+		if err != nil {
+			return err
+		}
+
+		for _, key := range keys {
+			if m.isMoved(key) {
+				continue
+			}
+			m.add(&m.stats.Scanned)
+
+			if err := m.limiter.wait(ctx); err != nil {
+				return err
+			}
+			newKey := m.to.Key(m.from.Strip(key))
+			moved, err := m.move(ctx, node, dumper, key, newKey)
+			if err != nil {
+				return fmt.Errorf("%s: %w", key, err)
+			}
+			if moved {
+				m.add(&m.stats.Migrated)
+				if !m.opts.DryRun && strings.HasPrefix(newKey, head) {
+					m.markMoved(newKey)
+				}
+			} else {
+				m.add(&m.stats.Skipped)
+			}
+		}
+		m.progress()
+
+		if next == 0 {
+			return nil
+		}
+		cursor = next
+	}
+}
+
+// Move a key, returns false if it is skipped.
+func (m *migration) move(ctx context.Context, node redis.Cmdable, dumper redis.Cmdable, key string, newKey string) (bool, error) {
+	if m.opts.DryRun {
+		if m.opts.Replace {
+			return true, nil
+		}
+		// in cluster and ring modes, the new key may be on another node
+		reader := node
+		if dumper != nil {
+			reader = dumper
+		}
+		n, err := reader.Exists(ctx, newKey).Result()
+		return n == 0, err
+	}
+
+	if dumper != nil {
+		return m.restore(ctx, node, dumper, key, newKey)
+	}
+
+	if m.opts.Copy {
+		n, err := node.Copy(ctx, key, newKey, 0, m.opts.Replace).Result()
+		return n == 1, err
+	}
+	if m.opts.Replace {
+		err := node.Rename(ctx, key, newKey).Err()
+		if isNoSuchKey(err) {
+			return false, nil // expired meanwhile
+		}
+		return err == nil, err
+	}
+	ok, err := node.RenameNX(ctx, key, newKey).Result()
+	if isNoSuchKey(err) {
+		return false, nil
+	}
+	return ok, err
+}
+
+// Move a key by DUMP and RESTORE with its remaining TTL, then delete it unless copying.
+// If the server does not know DUMP, e.g. it is disabled by the hosting service, the value is read and written again.
+func (m *migration) restore(ctx context.Context, node redis.Cmdable, dumper redis.Cmdable, key string, newKey string) (bool, error) {
+	var moved bool
+	value, err := node.Dump(ctx, key).Result()
+	switch {
+	case err == redis.Nil:
+		return false, nil // expired meanwhile
+	case isUnknownCommand(err):
+		moved, err = m.rewrite(ctx, node, dumper, key, newKey)
+	case err != nil:
+		return false, err
+	default:
+		moved, err = m.restoreDump(ctx, node, dumper, key, newKey, value)
+	}
+	if err != nil || !moved {
+		return false, err
+	}
+
+	if !m.opts.Copy {
+		return true, node.Del(ctx, key).Err()
+	}
+	return true, nil
+}
+
+// RESTORE the value dumped from key as newKey, returns false if it is skipped.
+func (m *migration) restoreDump(ctx context.Context, node redis.Cmdable, dumper redis.Cmdable, key string, newKey string, value string) (bool, error) {
+	ttl, ok, err := keyTTL(ctx, node, key)
+	if !ok || err != nil {
+		return false, err
+	}
+
+	if m.opts.Replace {
+		err = dumper.RestoreReplace(ctx, newKey, ttl, value).Err()
+	} else {
+		err = dumper.Restore(ctx, newKey, ttl, value).Err()
+	}
+	if err != nil && strings.HasPrefix(err.Error(), "BUSYKEY") {
+		return false, nil // exists under the new prefix
+	}
+	return err == nil, err
+}
+
+// Read the value of key by its type and write it as newKey in a transaction, returns false if it is skipped.
+// Only strings, hashes, lists, sets and sorted sets are supported. Unlike RESTORE, the new key is checked
+// before the transaction, so a key written under the new prefix meanwhile may be overwritten.
+func (m *migration) rewrite(ctx context.Context, node redis.Cmdable, dumper redis.Cmdable, key string, newKey string) (bool, error) {
+	typ, err := node.Type(ctx, key).Result()
+	if err != nil || typ == "none" {
+		return false, err
+	}
+	ttl, ok, err := keyTTL(ctx, node, key)
+	if !ok || err != nil {
+		return false, err
+	}
+	if !m.opts.Replace {
+		n, err := dumper.Exists(ctx, newKey).Result()
+		if n > 0 || err != nil {
+			return false, err
+		}
+	}
+
+	var write func(pipe redis.Pipeliner)
+	var size int
+	switch typ {
+	case "string":
+		v, err := node.Get(ctx, key).Result()
+		if err == redis.Nil {
+			return false, nil // expired meanwhile
+		}
+		if err != nil {
+			return false, err
+		}
+		size = 1
+		write = func(pipe redis.Pipeliner) { pipe.Set(ctx, newKey, v, 0) }
+	case "hash":
+		v, err := node.HGetAll(ctx, key).Result()
+		if err != nil {
+			return false, err
+		}
+		size = len(v)
+		write = func(pipe redis.Pipeliner) { pipe.HSet(ctx, newKey, v) }
+	case "list":
+		v, err := node.LRange(ctx, key, 0, -1).Result()
+		if err != nil {
+			return false, err
+		}
+		size = len(v)
+		write = func(pipe redis.Pipeliner) { pipe.RPush(ctx, newKey, v) }
+	case "set":
+		v, err := node.SMembers(ctx, key).Result()
+		if err != nil {
+			return false, err
+		}
+		size = len(v)
+		write = func(pipe redis.Pipeliner) { pipe.SAdd(ctx, newKey, v) }
+	case "zset":
+		v, err := node.ZRangeWithScores(ctx, key, 0, -1).Result()
+		if err != nil {
+			return false, err
+		}
+		members := make([]*redis.Z, len(v))
+		for i := range v {
+			members[i] = &v[i]
+		}
+		size = len(v)
+		write = func(pipe redis.Pipeliner) { pipe.ZAdd(ctx, newKey, members...) }
+	default:
+		return false, fmt.Errorf("can not migrate a %s without DUMP", typ)
+	}
+	if size == 0 {
+		return false, nil // expired meanwhile
+	}
+
+	_, err = dumper.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Del(ctx, newKey)
+		write(pipe)
+		if ttl > 0 {
+			pipe.PExpire(ctx, newKey, ttl)
+		}
+		return nil
+	})
+	return err == nil, err
+}
+
+// Returns the remaining TTL of key, 0 if it does not expire, or false if it does not exist anymore.
+func keyTTL(ctx context.Context, node redis.Cmdable, key string) (time.Duration, bool, error) {
+	ttl, err := node.PTTL(ctx, key).Result()
+	if err != nil {
+		return 0, false, err
+	}
+	switch {
+	case ttl == -2:
+		return 0, false, nil // expired meanwhile
+	case ttl < 0:
+		return 0, true, nil // no expiration
+	}
+	return ttl, true, nil
+}
+
+func (m *migration) add(counter *int64) {
+	m.mu.Lock()
+	*counter++
+	m.mu.Unlock()
+}
+
+// Returns true if key is a new key of this migration, which SCAN may return again under the old prefix.
+func (m *migration) isMoved(key string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	_, ok := m.moved[key]
+	return ok
+}
+
+func (m *migration) markMoved(key string) {
+	m.mu.Lock()
+	m.moved[key] = struct{}{}
+	m.mu.Unlock()
+}
+
+func (m *migration) snapshot() MigrateStats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.stats
+}
+
+// Report the stats so far to [MigrateOptions].Progress.
+func (m *migration) progress() {
+	if m.opts.Progress == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.opts.Progress(m.stats)
+}
+
+// Returns true if the server does not know the command.
+func isUnknownCommand(err error) bool {
+	return err != nil && strings.HasPrefix(err.Error(), "ERR unknown command")
+}
+
+// Returns true if the key of RENAME does not exist.
+func isNoSuchKey(err error) bool {
+	return err != nil && strings.HasPrefix(err.Error(), "ERR no such key")
+}
+
+// Escape the glob characters of SCAN MATCH.
+func escapeGlob(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch r {
+		case '*', '?', '[', ']', '\\':
+			b.WriteRune('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// Spaces the calls of wait by interval, shared by the goroutines of a migration.
+type rateLimiter struct {
+	interval time.Duration
+
+	mu   sync.Mutex
+	next time.Time
+}
+
+// Wait for the next slot, or until ctx is done. A nil limiter never waits.
+func (l *rateLimiter) wait(ctx context.Context) error {
+	if l == nil {
+		return nil
+	}
+
+	l.mu.Lock()
+	now := time.Now()
+	if l.next.Before(now) {
+		l.next = now
+	}
+	wait := l.next.Sub(now)
+	l.next = l.next.Add(l.interval)
+	l.mu.Unlock()
+
+	if wait <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,233 @@
+package goredis_test
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/hecigo/goredis"
+	"github.com/hecigo/goutils"
+	. "gopkg.in/check.v1"
+)
+
+type MigrateSuite struct{}
+
+var _ = Suite(&MigrateSuite{})
+
+func (s *MigrateSuite) SetUpSuite(c *C) {
+	fmt.Println("SetUpSuite > MigrateSuite")
+	goutils.QuickLoad()
+}
+
+func (s *MigrateSuite) SetUpTest(c *C) {
+	c.Assert(goredis.OpenWithConfig(&goredis.Config{ConnectionName: "migrate", KeyPrefix: "test-migrate"}), IsNil)
+}
+
+func (s *MigrateSuite) TearDownTest(c *C) {
+	goredis.Close("migrate")
+}
+
+// Test the keys are renamed with their TTLs, and the keys which exist under the new prefix are skipped
+func (s *MigrateSuite) TestRename(c *C) {
+	ctx := goredis.WithConnection(context.Background(), "migrate")
+	client := goredis.Client(ctx)
+	s.clean(c, "test-migrate-rename")
+	s.clean(c, "test-migrate-moved")
+	c.Assert(client.Set(ctx, "test-migrate-rename.a", "a", time.Hour).Err(), IsNil)
+	c.Assert(client.Set(ctx, "test-migrate-rename.b", "b", 0).Err(), IsNil)
+	c.Assert(client.Set(ctx, "test-migrate-rename.c", "c", 0).Err(), IsNil)
+	c.Assert(client.Set(ctx, "test-migrate-moved.c", "new", 0).Err(), IsNil)
+	c.Assert(client.Set(ctx, "test-migrate-renamed.d", "other", 0).Err(), IsNil)
+
+	var batches int
+	stats, err := goredis.MigratePrefix(ctx, "test-migrate-rename", "test-migrate-moved", &goredis.MigrateOptions{
+		ScanCount: 1,
+		Progress:  func(goredis.MigrateStats) { batches++ },
+	})
+	c.Assert(err, IsNil)
+	c.Assert(stats, Equals, goredis.MigrateStats{Scanned: 3, Migrated: 2, Skipped: 1})
+	c.Assert(batches > 0, Equals, true)
+
+	c.Assert(client.Get(ctx, "test-migrate-moved.a").Val(), Equals, "a")
+	c.Assert(client.TTL(ctx, "test-migrate-moved.a").Val() > 0, Equals, true)
+	c.Assert(client.Get(ctx, "test-migrate-moved.b").Val(), Equals, "b")
+	c.Assert(client.Exists(ctx, "test-migrate-rename.a", "test-migrate-rename.b").Val(), Equals, int64(0))
+
+	// skipped, the new key is kept
+	c.Assert(client.Get(ctx, "test-migrate-moved.c").Val(), Equals, "new")
+	c.Assert(client.Get(ctx, "test-migrate-rename.c").Val(), Equals, "c")
+	c.Assert(client.Get(ctx, "test-migrate-renamed.d").Val(), Equals, "other")
+
+	// overwritten with Replace
+	stats, err = goredis.MigratePrefix(ctx, "test-migrate-rename", "test-migrate-moved", &goredis.MigrateOptions{Replace: true})
+	c.Assert(err, IsNil)
+	c.Assert(stats, Equals, goredis.MigrateStats{Scanned: 1, Migrated: 1})
+	c.Assert(client.Get(ctx, "test-migrate-moved.c").Val(), Equals, "c")
+}
+
+// Test the prefixes which start with each other, the moved keys are not moved again but the old keys always are
+func (s *MigrateSuite) TestNestedPrefix(c *C) {
+	ctx := goredis.WithConnection(context.Background(), "migrate")
+	client := goredis.Client(ctx)
+	s.clean(c, "test-migrate-app")
+	c.Assert(client.Set(ctx, "test-migrate-app.a", "a", 0).Err(), IsNil)
+	c.Assert(client.Set(ctx, "test-migrate-app.b", "b", 0).Err(), IsNil)
+	c.Assert(client.Set(ctx, "test-migrate-app.v2.c", "c", 0).Err(), IsNil)
+
+	// `app` to `app.v2`, the old key `app.v2.c` is moved too
+	stats, err := goredis.MigratePrefix(ctx, "test-migrate-app", "test-migrate-app.v2", &goredis.MigrateOptions{ScanCount: 1})
+	c.Assert(err, IsNil)
+	c.Assert(stats, Equals, goredis.MigrateStats{Scanned: 3, Migrated: 3})
+	keys, err := client.Keys(ctx, "test-migrate-app.*").Result()
+	c.Assert(err, IsNil)
+	sort.Strings(keys)
+	c.Assert(keys, DeepEquals, []string{"test-migrate-app.v2.a", "test-migrate-app.v2.b", "test-migrate-app.v2.v2.c"})
+
+	// `app.v2` to `app`, back to where they were
+	stats, err = goredis.MigratePrefix(ctx, "test-migrate-app.v2", "test-migrate-app", &goredis.MigrateOptions{ScanCount: 1})
+	c.Assert(err, IsNil)
+	c.Assert(stats, Equals, goredis.MigrateStats{Scanned: 3, Migrated: 3})
+	keys, err = client.Keys(ctx, "test-migrate-app.*").Result()
+	c.Assert(err, IsNil)
+	sort.Strings(keys)
+	c.Assert(keys, DeepEquals, []string{"test-migrate-app.a", "test-migrate-app.b", "test-migrate-app.v2.c"})
+	c.Assert(client.Get(ctx, "test-migrate-app.v2.c").Val(), Equals, "c")
+}
+
+// Test the dry-run mode writes nothing, and the copies keep the old keys
+func (s *MigrateSuite) TestCopy(c *C) {
+	ctx := goredis.WithConnection(context.Background(), "migrate")
+	client := goredis.Client(ctx)
+	s.clean(c, "test-migrate-copy")
+	s.clean(c, "test-migrate-copied")
+	c.Assert(client.Set(ctx, "test-migrate-copy.a", "a", 0).Err(), IsNil)
+	c.Assert(client.Set(ctx, "test-migrate-copy.b", "b", 0).Err(), IsNil)
+	c.Assert(client.Set(ctx, "test-migrate-copied.b", "new", 0).Err(), IsNil)
+
+	stats, err := goredis.MigratePrefix(ctx, "test-migrate-copy", "test-migrate-copied", &goredis.MigrateOptions{DryRun: true})
+	c.Assert(err, IsNil)
+	c.Assert(stats, Equals, goredis.MigrateStats{Scanned: 2, Migrated: 1, Skipped: 1})
+	c.Assert(client.Exists(ctx, "test-migrate-copied.a").Val(), Equals, int64(0))
+
+	start := time.Now()
+	stats, err = goredis.MigratePrefix(ctx, "test-migrate-copy", "test-migrate-copied", &goredis.MigrateOptions{Copy: true, Rate: 10})
+	c.Assert(err, IsNil)
+	c.Assert(stats, Equals, goredis.MigrateStats{Scanned: 2, Migrated: 1, Skipped: 1})
+	c.Assert(time.Since(start) >= 100*time.Millisecond, Equals, true)
+
+	c.Assert(client.Get(ctx, "test-migrate-copied.a").Val(), Equals, "a")
+	c.Assert(client.Get(ctx, "test-migrate-copied.b").Val(), Equals, "new")
+	c.Assert(client.Exists(ctx, "test-migrate-copy.a", "test-migrate-copy.b").Val(), Equals, int64(2))
+}
+
+// Test the keys of every type are moved across the shards of a ring with their TTLs
+func (s *MigrateSuite) TestRing(c *C) {
+	c.Assert(goredis.OpenWithConfig(&goredis.Config{
+		ConnectionName: "migrate-ring",
+		Mode:           goredis.Mode_Ring,
+		Addresses:      []string{"localhost:6379", "localhost:6380"},
+	}), IsNil)
+	defer goredis.Close("migrate-ring")
+	ctx := goredis.WithConnection(context.Background(), "migrate-ring")
+	client := goredis.Client(ctx)
+
+	// the ring has no KEYS across the shards
+	c.Assert(client.Del(ctx, "test-migrate-ring.str", "test-migrate-ring.hash", "test-migrate-ring.list",
+		"test-migrate-ring.set", "test-migrate-ring.zset", "test-migrate-ring.busy").Err(), IsNil)
+	c.Assert(client.Del(ctx, "test-migrate-ringed.str", "test-migrate-ringed.hash", "test-migrate-ringed.list",
+		"test-migrate-ringed.set", "test-migrate-ringed.zset", "test-migrate-ringed.busy").Err(), IsNil)
+
+	c.Assert(client.Set(ctx, "test-migrate-ring.str", "a", time.Hour).Err(), IsNil)
+	c.Assert(client.HSet(ctx, "test-migrate-ring.hash", "f1", "v1", "f2", "v2").Err(), IsNil)
+	c.Assert(client.RPush(ctx, "test-migrate-ring.list", "1", "2", "3").Err(), IsNil)
+	c.Assert(client.SAdd(ctx, "test-migrate-ring.set", "x", "y").Err(), IsNil)
+	c.Assert(client.ZAdd(ctx, "test-migrate-ring.zset", &redis.Z{Score: 1, Member: "a"}, &redis.Z{Score: 2, Member: "b"}).Err(), IsNil)
+	c.Assert(client.Set(ctx, "test-migrate-ring.busy", "old", 0).Err(), IsNil)
+	c.Assert(client.Set(ctx, "test-migrate-ringed.busy", "new", 0).Err(), IsNil)
+
+	stats, err := goredis.MigratePrefix(ctx, "test-migrate-ring", "test-migrate-ringed", nil)
+	c.Assert(err, IsNil)
+	c.Assert(stats, Equals, goredis.MigrateStats{Scanned: 6, Migrated: 5, Skipped: 1})
+
+	c.Assert(client.Get(ctx, "test-migrate-ringed.str").Val(), Equals, "a")
+	c.Assert(client.TTL(ctx, "test-migrate-ringed.str").Val() > 0, Equals, true)
+	c.Assert(client.HGetAll(ctx, "test-migrate-ringed.hash").Val(), DeepEquals, map[string]string{"f1": "v1", "f2": "v2"})
+	c.Assert(client.LRange(ctx, "test-migrate-ringed.list", 0, -1).Val(), DeepEquals, []string{"1", "2", "3"})
+	members := client.SMembers(ctx, "test-migrate-ringed.set").Val()
+	sort.Strings(members)
+	c.Assert(members, DeepEquals, []string{"x", "y"})
+	c.Assert(client.ZRangeWithScores(ctx, "test-migrate-ringed.zset", 0, -1).Val(), DeepEquals,
+		[]redis.Z{{Score: 1, Member: "a"}, {Score: 2, Member: "b"}})
+	c.Assert(client.Exists(ctx, "test-migrate-ring.str", "test-migrate-ring.hash", "test-migrate-ring.list",
+		"test-migrate-ring.set", "test-migrate-ring.zset").Val(), Equals, int64(0))
+
+	// skipped, the new key is kept
+	c.Assert(client.Get(ctx, "test-migrate-ringed.busy").Val(), Equals, "new")
+	c.Assert(client.Get(ctx, "test-migrate-ring.busy").Val(), Equals, "old")
+
+	// overwritten with Replace, and kept with Copy
+	stats, err = goredis.MigratePrefix(ctx, "test-migrate-ring", "test-migrate-ringed", &goredis.MigrateOptions{Replace: true, Copy: true})
+	c.Assert(err, IsNil)
+	c.Assert(stats, Equals, goredis.MigrateStats{Scanned: 1, Migrated: 1})
+	c.Assert(client.Get(ctx, "test-migrate-ringed.busy").Val(), Equals, "old")
+	c.Assert(client.Get(ctx, "test-migrate-ring.busy").Val(), Equals, "old")
+}
+
+// Test the keys are migrated no faster than the rate
+func (s *MigrateSuite) TestRate(c *C) {
+	ctx := goredis.WithConnection(context.Background(), "migrate")
+	client := goredis.Client(ctx)
+	s.clean(c, "test-migrate-rate")
+	s.clean(c, "test-migrate-rated")
+	for i := 0; i < 6; i++ {
+		c.Assert(client.Set(ctx, fmt.Sprintf("test-migrate-rate.%d", i), i, 0).Err(), IsNil)
+	}
+
+	// 6 keys at 10 per second, the first one is not delayed
+	start := time.Now()
+	stats, err := goredis.MigratePrefix(ctx, "test-migrate-rate", "test-migrate-rated", &goredis.MigrateOptions{Rate: 10})
+	c.Assert(err, IsNil)
+	c.Assert(stats, Equals, goredis.MigrateStats{Scanned: 6, Migrated: 6})
+	c.Assert(time.Since(start) >= 500*time.Millisecond, Equals, true)
+
+	// the same keys without a rate
+	start = time.Now()
+	stats, err = goredis.MigratePrefix(ctx, "test-migrate-rated", "test-migrate-rate", nil)
+	c.Assert(err, IsNil)
+	c.Assert(stats, Equals, goredis.MigrateStats{Scanned: 6, Migrated: 6})
+	c.Assert(time.Since(start) < 500*time.Millisecond, Equals, true)
+
+	// stopped by the context while waiting
+	timeout, cancel := context.WithTimeout(ctx, 150*time.Millisecond)
+	defer cancel()
+	stats, err = goredis.MigratePrefix(timeout, "test-migrate-rate", "test-migrate-rated", &goredis.MigrateOptions{Rate: 10})
+	c.Assert(err, ErrorMatches, ".*context deadline exceeded")
+	c.Assert(stats.Migrated, Equals, int64(2))
+}
+
+// Test the prefixes and options are validated
+func (s *MigrateSuite) TestValidation(c *C) {
+	ctx := goredis.WithConnection(context.Background(), "migrate")
+	_, err := goredis.MigratePrefix(ctx, "test-migrate", "test-migrate", nil)
+	c.Assert(err, ErrorMatches, "redis\\[migrate\\]: the prefixes must be different")
+	_, err = goredis.MigratePrefix(ctx, "", "test-migrate", nil)
+	c.Assert(err, ErrorMatches, ".*the prefixes must not be empty")
+	_, err = goredis.MigratePrefix(ctx, "test-migrate", "test-migrate2", &goredis.MigrateOptions{Rate: -1})
+	c.Assert(err, ErrorMatches, ".*the rate must not be negative")
+
+	_, err = goredis.MigratePrefix(goredis.WithConnection(ctx, "missing"), "test-migrate", "test-migrate2", nil)
+	c.Assert(err, Equals, goredis.ErrConnectionNotOpened{Name: "missing"})
+}
+
+// Delete the keys under prefix left by the previous runs
+func (s *MigrateSuite) clean(c *C, prefix string) {
+	ctx := goredis.WithConnection(context.Background(), "migrate")
+	client := goredis.Client(ctx)
+	keys, err := client.Keys(ctx, prefix+".*").Result()
+	c.Assert(err, IsNil)
+	if len(keys) > 0 {
+		c.Assert(client.Del(ctx, keys...).Err(), IsNil)
+	}
+}
@@ -0,0 +1,136 @@
+package goredis
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// The deployment mode of Redis.
+type Mode string
+
+const (
+	Mode_Auto       Mode = ""           // guess the mode from addresses and master name, like [redis.NewUniversalClient]
+	Mode_Standalone Mode = "standalone" // single node, [redis.NewClient]
+	Mode_Failover   Mode = "failover"   // Redis Sentinel, [redis.NewFailoverClient]
+	Mode_Cluster    Mode = "cluster"    // Redis Cluster, [redis.NewClusterClient]
+	Mode_Ring       Mode = "ring"       // consistent hashing across independent shards, [redis.NewRing]
+)
+
+// Parse the mode from string. `sentinel` is an alias of [Mode_Failover], and `auto` of [Mode_Auto].
+func ParseMode(s string) (Mode, error) {
+	switch m := Mode(strings.ToLower(strings.TrimSpace(s))); m {
+	case Mode_Auto, "auto":
+		return Mode_Auto, nil
+	case "sentinel":
+		return Mode_Failover, nil
+	case Mode_Standalone, Mode_Failover, Mode_Cluster, Mode_Ring:
+		return m, nil
+	default:
+		return Mode_Auto, fmt.Errorf("unknown mode `%s`", s)
+	}
+}
+
+// Returns the mode name to print. [Mode_Auto] is printed as `auto`.
+func (m Mode) String() string {
+	if m == Mode_Auto {
+		return "auto"
+	}
+	return string(m)
+}
+
+// Returns the mode the client is created with. [Mode_Auto] is resolved like [redis.NewUniversalClient].
+func (cfg *Config) resolvedMode() Mode {
+	if cfg.Mode != Mode_Auto {
+		return cfg.Mode
+	}
+	switch {
+	case cfg.MasterName != "":
+		return Mode_Failover
+	case len(cfg.Addresses) > 1:
+		return Mode_Cluster
+	default:
+		return Mode_Standalone
+	}
+}
+
+// Validate the other settings fit the mode.
+func (cfg *Config) validateMode() error {
+	if len(cfg.Addresses) == 0 {
+		return errors.New("at least one address is required")
+	}
+
+	switch cfg.Mode {
+	case Mode_Auto:
+		return nil
+	case Mode_Standalone:
+		if len(cfg.Addresses) != 1 {
+			return fmt.Errorf("mode `%s` requires exactly one address, got %d", cfg.Mode, len(cfg.Addresses))
+		}
+		if cfg.MasterName != "" {
+			return fmt.Errorf("mode `%s` does not support master name", cfg.Mode)
+		}
+	case Mode_Failover:
+		if cfg.MasterName == "" {
+			return fmt.Errorf("mode `%s` requires master name", cfg.Mode)
+		}
+	case Mode_Cluster:
+		if cfg.DB != 0 {
+			return fmt.Errorf("mode `%s` supports only DB 0, got %d", cfg.Mode, cfg.DB)
+		}
+		if cfg.MasterName != "" {
+			return fmt.Errorf("mode `%s` does not support master name", cfg.Mode)
+		}
+	case Mode_Ring:
+		if cfg.MasterName != "" {
+			return fmt.Errorf("mode `%s` does not support master name", cfg.Mode)
+		}
+	default:
+		return fmt.Errorf("unknown mode `%s`", cfg.Mode)
+	}
+	return nil
+}
+
+// Create the Redis client of the mode from the universal options.
+func (cfg *Config) newClient(opts *redis.UniversalOptions) redis.UniversalClient {
+	switch cfg.Mode {
+	case Mode_Standalone:
+		return redis.NewClient(opts.Simple())
+	case Mode_Failover:
+		return redis.NewFailoverClient(opts.Failover())
+	case Mode_Cluster:
+		return redis.NewClusterClient(opts.Cluster())
+	case Mode_Ring:
+		// use the address as shard name, so that the keys are distributed the same way when the addresses are reordered
+		shards := make(map[string]string)
+		for _, addr := range opts.Addrs {
+			shards[addr] = addr
+		}
+		return redis.NewRing(&redis.RingOptions{
+			Addrs:              shards,
+			Dialer:             opts.Dialer,
+			OnConnect:          opts.OnConnect,
+			Username:           opts.Username,
+			Password:           opts.Password,
+			DB:                 opts.DB,
+			MaxRetries:         opts.MaxRetries,
+			MinRetryBackoff:    opts.MinRetryBackoff,
+			MaxRetryBackoff:    opts.MaxRetryBackoff,
+			DialTimeout:        opts.DialTimeout,
+			ReadTimeout:        opts.ReadTimeout,
+			WriteTimeout:       opts.WriteTimeout,
+			PoolFIFO:           opts.PoolFIFO,
+			PoolSize:           opts.PoolSize,
+			MinIdleConns:       opts.MinIdleConns,
+			MaxConnAge:         opts.MaxConnAge,
+			PoolTimeout:        opts.PoolTimeout,
+			IdleTimeout:        opts.IdleTimeout,
+			IdleCheckFrequency: opts.IdleCheckFrequency,
+			TLSConfig:          opts.TLSConfig,
+		})
+	default:
+		return redis.NewUniversalClient(opts)
+	}
+}
@@ -12,6 +12,12 @@ import (
 type RankingBoard struct {
 	Id      string          `json:"id"`
 	Context context.Context `json:"-"`
+
+	// the error of [GetRankingBoard], returned by every method of the ranking board
+	err error
+
+	// the key without prefix, to get the ranking board on the fallback connection
+	name string
 }
 
 type RankingUpsertKind int
@@ -22,8 +28,19 @@ const (
 )
 
 // Get Redis client
-func (r *RankingBoard) redis() redis.UniversalClient {
-	return Client(r.Context)
+func (r *RankingBoard) redis() (redis.UniversalClient, error) {
+	if r.err != nil {
+		return nil, r.err
+	}
+	return ClientE(r.Context)
+}
+
+// Get the connection, its reader follows [CtxKey_ReadPreference] in context
+func (r *RankingBoard) conn() (*connection, error) {
+	if r.err != nil {
+		return nil, r.err
+	}
+	return getConn(r.Context)
 }
 
 // Initialize a ranking board, and return a RankingBoard instance.
@@ -33,17 +50,49 @@ func (r *RankingBoard) redis() redis.UniversalClient {
 //   - At least one argument is required, the first argument is [RankingBoard].Id.
 //     It is also the key of the sorted-set in redis.
 //   - The second argument is optional, it is the name of the redis connection.
+//
+// The key is built by the [KeyBuilder] of the call, see [WithKeyPrefix], [WithKeySegments] and [WithRawKeys].
+//
+// If the connection is not opened, every method of the ranking board returns [ErrConnectionNotOpened].
+// If the connection is unavailable, the reads are retried on [Config].FallbackConnection,
+// and the updates too if [Config].FailoverWrites is enabled.
 func GetRankingBoard(ctx context.Context, args ...string) *RankingBoard {
 	if len(args) == 0 {
 		return nil
 	}
 
+	cfg, err := GetConfigE(ctx)
+	if err != nil {
+		return &RankingBoard{Context: ctx, err: err}
+	}
+
+	kb, err := newKeyBuilder(ctx, cfg)
+	if err != nil {
+		return &RankingBoard{Context: ctx, err: err}
+	}
+
+	name := strings.Join(args, "_")
 	return &RankingBoard{
-		Id:      GetConfig(ctx).KeyPrefix + "." + strings.Join(args, "_"),
+		Id:      kb.Key(name),
 		Context: ctx,
+		name:    name,
 	}
 }
 
+// Returns the ranking board on the connection of ctx, it is r itself unless the call fails over.
+func (r *RankingBoard) on(ctx context.Context) *RankingBoard {
+	// a wrongly typed name is reported by the call itself
+	name, _ := ctxConnName("default", ctx)
+	if current, _ := ctxConnName("default", r.Context); name == current {
+		return r
+	}
+	if r.name == "" {
+		// built without GetRankingBoard, the key is used as it is
+		return &RankingBoard{Id: r.Id, Context: ctx}
+	}
+	return GetRankingBoard(ctx, r.name)
+}
+
 // Add a member to the ranking board with a score.
 // Don't like [IncrBy], this method will overwrite the score of the member if it already exists.
 // If a specified member is already in the sorted-set,
@@ -51,7 +100,21 @@ func GetRankingBoard(ctx context.Context, args ...string) *RankingBoard {
 // By default, only update existing elements if the new score is greater than the current score,
 // unless [kind] is set to [Upsert_LessThan]. This option doesn't prevent adding new elements.
 func (r *RankingBoard) Upsert(member string, score float64, kind ...RankingUpsertKind) error {
-	_, err := r.redis().ZAddArgs(r.Context, r.Id, redis.ZAddArgs{
+	return withShadow(r.Context, "default", "RankingBoard.Upsert "+r.Id, func(ctx context.Context) error {
+		return r.on(ctx).upsert(member, score, kind...)
+	})
+}
+
+func (r *RankingBoard) upsert(member string, score float64, kind ...RankingUpsertKind) error {
+	client, err := r.redis()
+	if err != nil {
+		return err
+	}
+
+	ctx, span := startSpan(r.Context, "RankingBoard.Upsert", r.Id)
+	defer span.End()
+
+	_, err = client.ZAddArgs(ctx, r.Id, redis.ZAddArgs{
 		GT:      len(kind) == 0 || (len(kind) > 0 && kind[0] == Upsert_GreaterThan),
 		LT:      len(kind) > 0 && kind[0] == Upsert_LessThan,
 		Members: []redis.Z{{Member: member, Score: score}},
@@ -62,10 +125,25 @@ func (r *RankingBoard) Upsert(member string, score float64, kind ...RankingUpser
 
 // Similar [Upsert], but supports multiple members. Recommended for batch operations.
 func (r *RankingBoard) UpsertMulti(members map[string]float64, kind ...RankingUpsertKind) error {
+	members = copyScores(members) // the shadow writes it in the background
+	return withShadow(r.Context, "default", "RankingBoard.UpsertMulti "+r.Id, func(ctx context.Context) error {
+		return r.on(ctx).upsertMulti(members, kind...)
+	})
+}
+
+func (r *RankingBoard) upsertMulti(members map[string]float64, kind ...RankingUpsertKind) error {
+	client, err := r.redis()
+	if err != nil {
+		return err
+	}
+
+	ctx, span := startSpan(r.Context, "RankingBoard.UpsertMulti", r.Id)
+	defer span.End()
+
 	// get by pipeline
-	cmds, err := r.redis().TxPipelined(r.Context, func(pipe redis.Pipeliner) error {
+	cmds, err := client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
 		for member, score := range members {
-			pipe.ZAddArgs(r.Context, r.Id, redis.ZAddArgs{
+			pipe.ZAddArgs(ctx, r.Id, redis.ZAddArgs{
 				GT:      len(kind) == 0 || (len(kind) > 0 && kind[0] == Upsert_GreaterThan),
 				LT:      len(kind) > 0 && kind[0] == Upsert_LessThan,
 				Members: []redis.Z{{Member: member, Score: score}},
@@ -92,17 +170,46 @@ func (r *RankingBoard) UpsertMulti(members map[string]float64, kind ...RankingUp
 // If the member does not exist, it is added with increment as its score.
 // Returns the new score of the member.
 func (r *RankingBoard) IncrBy(member string, increment float64) (float64, error) {
-	rs, err := r.redis().ZIncrBy(r.Context, r.Id, increment, member).Result()
+	return withShadowValue(r.Context, "default", "RankingBoard.IncrBy "+r.Id, func(ctx context.Context) (float64, error) {
+		return r.on(ctx).incrBy(member, increment)
+	})
+}
+
+func (r *RankingBoard) incrBy(member string, increment float64) (float64, error) {
+	client, err := r.redis()
+	if err != nil {
+		return 0, err
+	}
+
+	ctx, span := startSpan(r.Context, "RankingBoard.IncrBy", r.Id)
+	defer span.End()
+
+	rs, err := client.ZIncrBy(ctx, r.Id, increment, member).Result()
 	return rs, err
 }
 
 // Similar [IncrBy], but supports multiple members.
 // Returns a map of member => new score.
 func (r *RankingBoard) IncrByMulti(increments map[string]float64) (map[string]float64, error) {
+	increments = copyScores(increments) // the shadow writes it in the background
+	return withShadowValue(r.Context, "default", "RankingBoard.IncrByMulti "+r.Id, func(ctx context.Context) (map[string]float64, error) {
+		return r.on(ctx).incrByMulti(increments)
+	})
+}
+
+func (r *RankingBoard) incrByMulti(increments map[string]float64) (map[string]float64, error) {
+	client, err := r.redis()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, span := startSpan(r.Context, "RankingBoard.IncrByMulti", r.Id)
+	defer span.End()
+
 	// get by pipeline
-	cmds, err := r.redis().TxPipelined(r.Context, func(pipe redis.Pipeliner) error {
+	cmds, err := client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
 		for member, increment := range increments {
-			pipe.ZIncrBy(r.Context, r.Id, increment, member)
+			pipe.ZIncrBy(ctx, r.Id, increment, member)
 		}
 		return nil
 	})
@@ -128,7 +235,21 @@ func (r *RankingBoard) IncrByMulti(increments map[string]float64) (map[string]fl
 
 // Remove a member from the ranking board.
 func (r *RankingBoard) Remove(member string) error {
-	_, err := r.redis().ZRem(r.Context, r.Id, member).Result()
+	return withShadow(r.Context, "default", "RankingBoard.Remove "+r.Id, func(ctx context.Context) error {
+		return r.on(ctx).remove(member)
+	})
+}
+
+func (r *RankingBoard) remove(member string) error {
+	client, err := r.redis()
+	if err != nil {
+		return err
+	}
+
+	ctx, span := startSpan(r.Context, "RankingBoard.Remove", r.Id)
+	defer span.End()
+
+	_, err = client.ZRem(ctx, r.Id, member).Result()
 	return err
 }
 
@@ -136,7 +257,21 @@ func (r *RankingBoard) Remove(member string) error {
 // By default, the members are ordered from highest to lowest scores, unless [orderBy] is set to [false] (~ ascending).
 // Returns a map of member => score.
 func (r *RankingBoard) Top(n int64, orderBy ...bool) (map[string]float64, error) {
-	z, err := r.redis().ZRangeArgsWithScores(r.Context, redis.ZRangeArgs{
+	return withShadowRead(r.Context, "default", "RankingBoard.Top "+r.Id, func(ctx context.Context) (map[string]float64, error) {
+		return r.on(ctx).top(n, orderBy...)
+	})
+}
+
+func (r *RankingBoard) top(n int64, orderBy ...bool) (map[string]float64, error) {
+	conn, err := r.conn()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, span := startSpan(r.Context, "RankingBoard.Top", r.Id)
+	defer span.End()
+
+	z, err := conn.reader(ctx).ZRangeArgsWithScores(ctx, redis.ZRangeArgs{
 		Key:   r.Id,
 		Start: 0,
 		Stop:  n - 1,
@@ -159,7 +294,21 @@ func (r *RankingBoard) Top(n int64, orderBy ...bool) (map[string]float64, error)
 
 // Get score of a member in the ranking board.
 func (r *RankingBoard) Score(member string) (float64, error) {
-	result, err := r.redis().ZScore(r.Context, r.Id, member).Result()
+	return withShadowRead(r.Context, "default", "RankingBoard.Score "+r.Id, func(ctx context.Context) (float64, error) {
+		return r.on(ctx).score(member)
+	})
+}
+
+func (r *RankingBoard) score(member string) (float64, error) {
+	conn, err := r.conn()
+	if err != nil {
+		return 0, err
+	}
+
+	ctx, span := startSpan(r.Context, "RankingBoard.Score", r.Id)
+	defer span.End()
+
+	result, err := conn.reader(ctx).ZScore(ctx, r.Id, member).Result()
 	if err == redis.Nil {
 		return 0, nil
 	}
@@ -169,10 +318,25 @@ func (r *RankingBoard) Score(member string) (float64, error) {
 // Get scores of multiple members in the ranking board.
 // Returns a map of member => score.
 func (r *RankingBoard) Scores(members ...string) (map[string]float64, error) {
+	members = append([]string(nil), members...) // read again by the shadow in the background
+	return withShadowRead(r.Context, "default", "RankingBoard.Scores "+r.Id, func(ctx context.Context) (map[string]float64, error) {
+		return r.on(ctx).scores(members...)
+	})
+}
+
+func (r *RankingBoard) scores(members ...string) (map[string]float64, error) {
+	conn, err := r.conn()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, span := startSpan(r.Context, "RankingBoard.Scores", r.Id)
+	defer span.End()
+
 	// get by pipeline
-	cmds, err := r.redis().TxPipelined(r.Context, func(pipe redis.Pipeliner) error {
+	cmds, err := conn.readPipelined(ctx, func(pipe redis.Pipeliner) error {
 		for _, member := range members {
-			pipe.ZScore(r.Context, r.Id, member)
+			pipe.ZScore(ctx, r.Id, member)
 		}
 		return nil
 	})
@@ -203,12 +367,49 @@ func (r *RankingBoard) Scores(members ...string) (map[string]float64, error) {
 
 // Delete the ranking board.
 func (r *RankingBoard) Delete() error {
-	_, err := r.redis().Del(r.Context, r.Id).Result()
+	return withShadow(r.Context, "default", "RankingBoard.Delete "+r.Id, func(ctx context.Context) error {
+		return r.on(ctx).delete()
+	})
+}
+
+func (r *RankingBoard) delete() error {
+	client, err := r.redis()
+	if err != nil {
+		return err
+	}
+
+	ctx, span := startSpan(r.Context, "RankingBoard.Delete", r.Id)
+	defer span.End()
+
+	_, err = client.Del(ctx, r.Id).Result()
 	return err
 }
 
 // Set expiration time of the ranking board.
 func (r *RankingBoard) Expire(ttl time.Duration) error {
-	_, err := r.redis().Expire(r.Context, r.Id, ttl).Result()
+	return withShadow(r.Context, "default", "RankingBoard.Expire "+r.Id, func(ctx context.Context) error {
+		return r.on(ctx).expire(ttl)
+	})
+}
+
+func (r *RankingBoard) expire(ttl time.Duration) error {
+	client, err := r.redis()
+	if err != nil {
+		return err
+	}
+
+	ctx, span := startSpan(r.Context, "RankingBoard.Expire", r.Id)
+	defer span.End()
+
+	_, err = client.Expire(ctx, r.Id, ttl).Result()
 	return err
 }
+
+// Returns a copy of the scores of members.
+func copyScores(m map[string]float64) map[string]float64 {
+	r := make(map[string]float64, len(m))
+	for k, v := range m {
+		r[k] = v
+	}
+	return r
+}
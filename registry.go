@@ -0,0 +1,299 @@
+package goredis
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+
+	"github.com/go-redis/cache/v8"
+	"github.com/go-redis/redis/v8"
+	"github.com/hecigo/goutils"
+)
+
+// A named connection, the clients and the configuration are always swapped together.
+type connection struct {
+	client  redis.UniversalClient
+	replica redis.UniversalClient // serves the read commands from replicas, nil if [Config].ReadOnly is disabled
+	config  *Config
+	hooks   []redis.Hook // the user hooks applied to the clients, see [RegisterHook]
+	breaker *breaker     // shared by the clients, nil if [Config].BreakerThreshold is 0
+	shadow  *shadow      // mirrors the writes, nil if [Config].ShadowConnection is empty
+
+	source   configSource // loads the fresh configuration on [Reload], nil to reuse config
+	inflight atomic.Int64 // the number of in-flight commands, see [inflightHook]
+
+	closeOnce sync.Once
+	closeErr  error
+	closed    chan struct{} // closed by close, it ends the grace period of a retired connection
+}
+
+// Close the clients of the connection, and stop its shadow. It is safe to call more than once,
+// e.g. by [CloseAll] while the connection is drained after a reload.
+func (conn *connection) close() error {
+	conn.closeOnce.Do(func() {
+		close(conn.closed)
+		conn.shadow.stop()
+		conn.closeErr = conn.client.Close()
+		if conn.replica != nil {
+			if err := conn.replica.Close(); conn.closeErr == nil {
+				conn.closeErr = err
+			}
+		}
+	})
+	return conn.closeErr
+}
+
+// A named cache, built on top of the connection with the same name.
+type cacheEntry struct {
+	cache        *cache.Cache
+	replicaCache *cache.Cache // reads from replicas, nil if the connection has no replicas
+	local        cache.LocalCache
+	readOnly     bool    // [Config].ReadOnly of the connection
+	connConfig   *Config // the configuration of the connection, to build the keys if the call scopes them
+	config       *CacheConfig
+}
+
+// Returns the cache to serve reads, following [CtxKey_ReadPreference] in context.
+func (entry *cacheEntry) reader(ctx context.Context) *cache.Cache {
+	if entry.replicaCache != nil && useReplica(ctx, entry.readOnly) {
+		return entry.replicaCache
+	}
+	return entry.cache
+}
+
+// The registry of all connections and caches. It is safe for concurrent use.
+type registry struct {
+	mu     sync.RWMutex
+	conns  map[string]*connection
+	caches map[string]*cacheEntry
+	hooks  map[string][]redis.Hook // registered by [RegisterHook], kept across reopens
+	file   string                  // the path of the file loaded by [LoadConfigFile]
+
+	// the connections replaced by a reopen, which are drained and closed in the background
+	retired map[*connection]struct{}
+
+	// set by [CloseAll], the connections can not be used or reloaded until one of them is opened again
+	shutdown bool
+}
+
+var reg = &registry{
+	conns:  make(map[string]*connection),
+	caches: make(map[string]*cacheEntry),
+	hooks:  make(map[string][]redis.Hook),
+
+	retired: make(map[*connection]struct{}),
+}
+
+// Returns the connection with name, or nil if it is not opened.
+func (r *registry) conn(name string) *connection {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.conns[name]
+}
+
+// Returns a snapshot of all opened connections, sorted by name.
+func (r *registry) connList() []*connection {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	conns := make([]*connection, 0, len(r.conns))
+	for _, conn := range r.conns {
+		conns = append(conns, conn)
+	}
+	sort.Slice(conns, func(i, j int) bool {
+		return conns[i].config.ConnectionName < conns[j].config.ConnectionName
+	})
+	return conns
+}
+
+// Replace the connection with name and re-point its cache at the new client, and returns the old one to be closed
+// by the caller. The connection and its cache change together, so that the cache never outlives its client.
+// Callers will be handed the new client right after this function returns.
+// A reload fails with [ErrShutdown] after [CloseAll], while opening a connection starts the registry over.
+func (r *registry) swapConn(name string, conn *connection, reload bool) (*connection, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.shutdown {
+		if reload {
+			return nil, ErrShutdown
+		}
+		r.shutdown = false
+	}
+	old := r.conns[name]
+	r.conns[name] = conn
+	if entry := r.caches[name]; entry != nil {
+		r.caches[name] = newCacheEntry(conn, entry.config, entry.local)
+	}
+	return old, nil
+}
+
+// Drain and close the connection replaced by a reopen in the background, so that the reopen does not wait for it.
+// The callers which fetched its client before the reopen keep using it during the grace period, see [Config].DrainTimeout.
+// [CloseAll] does not wait for the grace period, but still waits for its in-flight commands.
+func (r *registry) retire(conn *connection) {
+	r.mu.Lock()
+	r.retired[conn] = struct{}{}
+	r.mu.Unlock()
+
+	go func() {
+		if err := conn.drainAndClose(); err != nil {
+			goutils.Errorf("redis[%s]: close old client: %s", conn.config.ConnectionName, err)
+		}
+		r.mu.Lock()
+		delete(r.retired, conn)
+		r.mu.Unlock()
+	}()
+}
+
+// Remove the connection with name, and returns it to be closed by the caller.
+func (r *registry) removeConn(name string) *connection {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	old := r.conns[name]
+	delete(r.conns, name)
+	return old
+}
+
+// Append a hook to the connection with name.
+func (r *registry) addHook(name string, hook redis.Hook) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.hooks[name] = append(r.hooks[name], hook)
+}
+
+// Returns a snapshot of the hooks registered to the connection with name.
+func (r *registry) hookList(name string) []redis.Hook {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return append([]redis.Hook(nil), r.hooks[name]...)
+}
+
+// Returns the path of the file loaded by [LoadConfigFile], or empty if no file is loaded.
+func (r *registry) configFilePath() string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.file
+}
+
+// Remember the path of the file loaded by [LoadConfigFile], to watch it.
+func (r *registry) setConfigFilePath(path string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.file = path
+}
+
+// Remove all connections and caches, and mark the registry as shut down.
+// Returns the connections to be drained and closed by the caller, including the ones still retiring.
+func (r *registry) shutdownAll() []*connection {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	conns := make([]*connection, 0, len(r.conns)+len(r.retired))
+	for _, conn := range r.conns {
+		conns = append(conns, conn)
+	}
+	for conn := range r.retired {
+		conns = append(conns, conn)
+	}
+	r.conns = make(map[string]*connection)
+	r.caches = make(map[string]*cacheEntry)
+	r.shutdown = true
+	return conns
+}
+
+// Returns true if the registry is shut down by [CloseAll].
+func (r *registry) isShutdown() bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.shutdown
+}
+
+// Returns the cache with name, or nil if it is not enabled.
+func (r *registry) cache(name string) *cacheEntry {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.caches[name]
+}
+
+// Returns a snapshot of all enabled caches, by name.
+func (r *registry) cacheMap() map[string]*cacheEntry {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	caches := make(map[string]*cacheEntry, len(r.caches))
+	for name, entry := range r.caches {
+		caches[name] = entry
+	}
+	return caches
+}
+
+// Create the cache on top of the current connection with name and register it,
+// or returns false if the connection is not opened.
+func (r *registry) addCache(name string, cfg *CacheConfig, local cache.LocalCache) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	conn := r.conns[name]
+	if conn == nil {
+		return false
+	}
+	r.caches[name] = newCacheEntry(conn, cfg, local)
+	return true
+}
+
+// Remove the cache with name, the connection is kept.
+func (r *registry) removeCache(name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.caches, name)
+}
+
+// Returns the connection from context, or [ErrConnectionNotOpened] if it is not opened.
+func getConn(ctx ...context.Context) (*connection, error) {
+	connName, err := ctxConnName("default", ctx...)
+	if err != nil {
+		return nil, err
+	}
+	conn := reg.conn(connName)
+	if conn == nil {
+		if reg.isShutdown() {
+			return nil, ErrShutdown
+		}
+		return nil, ErrConnectionNotOpened{Name: connName}
+	}
+	return conn, nil
+}
+
+// Returns the cache from context, or [ErrCacheNotEnabled] if it is not enabled.
+func getCacheEntry(ctx ...context.Context) (*cacheEntry, error) {
+	connName, err := ctxConnName("cache", ctx...)
+	if err != nil {
+		return nil, err
+	}
+	entry := reg.cache(connName)
+	if entry == nil {
+		if reg.isShutdown() {
+			return nil, ErrShutdown
+		}
+		return nil, ErrCacheNotEnabled{Name: connName}
+	}
+	return entry, nil
+}
+
+// Get the connection name from [goutils.CtxKey_ConnName] in context, or the fallback if it is not set.
+// Returns the fallback with an error if the value is not a string, so that the call never runs on the wrong connection.
+func ctxConnName(fallback string, ctx ...context.Context) (string, error) {
+	if len(ctx) == 0 || ctx[0] == nil {
+		return fallback, nil
+	}
+
+	switch v := ctx[0].Value(goutils.CtxKey_ConnName).(type) {
+	case nil:
+		return fallback, nil
+	case string:
+		if v == "" {
+			return fallback, nil
+		}
+		return v, nil
+	default:
+		return fallback, fmt.Errorf("redis: %s must be a string, got %T", goutils.CtxKey_ConnName, v)
+	}
+}
@@ -0,0 +1,137 @@
+package goredis_test
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/hecigo/goredis"
+	"github.com/hecigo/goutils"
+	. "gopkg.in/check.v1"
+)
+
+// Run with `go test -race` to detect data races on the connection registry.
+type RegistrySuite struct{}
+
+var _ = Suite(&RegistrySuite{})
+
+func (s *RegistrySuite) SetUpSuite(c *C) {
+	fmt.Println("SetUpSuite > RegistrySuite")
+	goutils.QuickLoad()
+}
+
+func (s *RegistrySuite) TearDownSuite(c *C) {
+	goredis.Close("race", "race2")
+}
+
+// Test reopening, closing and getting clients concurrently, the clients handed out are never closed under the callers
+func (s *RegistrySuite) TestConcurrentOpenClose(c *C) {
+	c.Assert(goredis.Open("race"), IsNil)
+
+	ctx := context.WithValue(context.Background(), goutils.CtxKey_ConnName, "race")
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(4)
+
+		// reopen and reload the connection while it is in use
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				c.Check(goredis.Open("race"), IsNil)
+				c.Check(goredis.Reload("race"), IsNil)
+			}
+		}()
+
+		// get the client and its configuration
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 200; j++ {
+				c.Check(goredis.Client(ctx), NotNil)
+				c.Check(goredis.GetConfig(ctx), NotNil)
+				goredis.Print("race")
+			}
+		}()
+
+		// run commands on the clients, which may be replaced meanwhile
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				client := goredis.Client(ctx)
+				c.Check(client.Ping(ctx).Err(), IsNil)
+				c.Check(client.Get(ctx, "test_race_missing").Err(), Equals, redis.Nil)
+			}
+		}()
+
+		// open and close another connection
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				c.Check(goredis.Open("race2"), IsNil)
+				c.Check(goredis.Close("race2"), IsNil)
+			}
+		}()
+	}
+	wg.Wait()
+
+	// the latest client is still usable
+	c.Assert(goredis.Client(ctx).Ping(ctx).Err(), IsNil)
+}
+
+// Test the cache follows the latest client when the connection is reopened concurrently
+func (s *RegistrySuite) TestConcurrentReopenCache(c *C) {
+	c.Assert(goredis.EnableCache("race"), IsNil)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				c.Check(goredis.Open("race"), IsNil)
+			}
+		}()
+	}
+	wg.Wait()
+
+	ctx := context.WithValue(context.Background(), goutils.CtxKey_ConnName, "race")
+	c.Assert(goredis.SetCache(ctx, "test_race_cache", "value"), IsNil)
+	cache, err := goredis.CacheE(ctx)
+	c.Assert(err, IsNil)
+	c.Assert(cache.Exists(ctx, "test_race_cache"), Equals, true)
+}
+
+// Test a disabled cache is not found anymore, but its connection is kept
+func (s *RegistrySuite) TestDisableCache(c *C) {
+	c.Assert(goredis.EnableCache("race"), IsNil)
+	ctx := context.WithValue(context.Background(), goutils.CtxKey_ConnName, "race")
+	c.Assert(goredis.SetCache(ctx, "test_disable_cache", "value"), IsNil)
+
+	goredis.DisableCache("race")
+	var v string
+	c.Assert(goredis.GetCache(ctx, "test_disable_cache", &v), Equals, goredis.ErrCacheNotEnabled{Name: "race"})
+	c.Assert(goredis.SetCache(ctx, "test_disable_cache", "value"), Equals, goredis.ErrCacheNotEnabled{Name: "race"})
+	_, err := goredis.ClientE(ctx)
+	c.Assert(err, IsNil)
+
+	// reopening does not enable it again
+	c.Assert(goredis.Open("race"), IsNil)
+	_, err = goredis.CacheE(ctx)
+	c.Assert(err, Equals, goredis.ErrCacheNotEnabled{Name: "race"})
+
+	c.Assert(goredis.EnableCache("race"), IsNil)
+	c.Assert(goredis.GetCache(ctx, "test_disable_cache", &v), IsNil)
+	c.Assert(v, Equals, "value")
+}
+
+// Test a reopened connection hands out the new client and configuration
+func (s *RegistrySuite) TestReopen(c *C) {
+	ctx := context.WithValue(context.Background(), goutils.CtxKey_ConnName, "race")
+	c.Assert(goredis.Open("race"), IsNil)
+	old := goredis.Client(ctx)
+
+	c.Assert(goredis.Open("race"), IsNil)
+	c.Assert(goredis.Client(ctx) != old, Equals, true)
+	c.Assert(goredis.GetConfig(ctx), NotNil)
+	c.Assert(goredis.Client(ctx).Ping(ctx).Err(), IsNil)
+}
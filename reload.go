@@ -0,0 +1,253 @@
+package goredis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"reflect"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/hecigo/goutils"
+)
+
+// Returns the fresh configuration of a connection on [Reload], e.g. from env or from the configuration file.
+type configSource func() (*Config, error)
+
+// Returns the source of the connection opened from env `REDIS<name>_*`.
+func envSource(connName string) configSource {
+	return func() (*Config, error) {
+		return loadConfig(connName)
+	}
+}
+
+// Returns the source of the connection opened from the configuration file, see [LoadConfigFile].
+func fileSource(path string, connName string) configSource {
+	return func() (*Config, error) {
+		file, err := ReadConfigFile(path)
+		if err != nil {
+			return nil, err
+		}
+		cfg, ok := file.Connections[connName]
+		if !ok {
+			return nil, fmt.Errorf("redis[%s]: not defined in %s anymore", connName, path)
+		}
+		return cfg, nil
+	}
+}
+
+// Reload the connections with name from their fresh configuration, e.g. to apply a new timeout or pool size
+// without a restart. If name is not provided, the default connection will be reloaded.
+//
+// The configuration is loaded again from where the connection was opened: env for [Open],
+// the file for [LoadConfigFile]. A connection opened by [OpenWithConfig] is rebuilt with the same configuration.
+// The new client is swapped in atomically and the caches are re-pointed at it. The old client keeps working
+// for the callers which fetched it before, then it is closed in the background after [Config].DrainTimeout
+// and its in-flight commands.
+//
+// The connections are reloaded one by one, a failure does not stop the others and the errors are joined.
+// A connection keeps its current client if it fails to reload. After [CloseAll], it returns [ErrShutdown].
+func Reload(name ...string) error {
+	if len(name) == 0 {
+		name = append(name, "default")
+	}
+	if reg.isShutdown() {
+		return ErrShutdown
+	}
+
+	var errs []error
+	for _, connName := range name {
+		conn := reg.conn(connName)
+		if conn == nil {
+			errs = append(errs, ErrConnectionNotOpened{Name: connName})
+			continue
+		}
+
+		cfg := conn.config
+		if conn.source != nil {
+			fresh, err := conn.source()
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			cfg = fresh
+		}
+
+		if err := openWithConfig(cfg, conn.source, true); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Watch the reload triggers in the background until ctx is done:
+//   - SIGHUP reloads every opened connection by [Reload]
+//   - a change of the file loaded by [LoadConfigFile] reads the file again, it is checked every interval.
+//     Only the connections and the caches which are new or configured differently are reopened,
+//     the others keep their clients and their local caches. Set interval to 0 to watch SIGHUP only.
+//
+// The reload errors are logged, the connections which fail to reload keep their current clients.
+func WatchReload(ctx context.Context, interval time.Duration) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	lastMod := configFileModTime()
+
+	go func() {
+		defer signal.Stop(hup)
+
+		var tick <-chan time.Time
+		if interval > 0 {
+			ticker := time.NewTicker(interval)
+			defer ticker.Stop()
+			tick = ticker.C
+		}
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-hup:
+				goutils.Printf("redis: SIGHUP received, reloading connections")
+				var names []string
+				for _, conn := range reg.connList() {
+					names = append(names, conn.config.ConnectionName)
+				}
+				if err := Reload(names...); err != nil {
+					goutils.Errorf("redis: reload: %s", err)
+				}
+			case <-tick:
+				mod := configFileModTime()
+				if mod.Equal(lastMod) {
+					continue
+				}
+				lastMod = mod
+				if path := reg.configFilePath(); path != "" && !reg.isShutdown() {
+					goutils.Printf("redis: %s changed, reloading it", path)
+					if err := reloadConfigFile(path); err != nil {
+						goutils.Errorf("redis: reload %s: %s", path, err)
+					}
+				}
+			}
+		}
+	}()
+}
+
+// Read the configuration file again, then reopen the connections and enable the caches which are new
+// or configured differently than the registered ones. A connection or a cache keeps its current one on error.
+func reloadConfigFile(path string) error {
+	file, err := ReadConfigFile(path)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, name := range file.connectionNames() {
+		cfg := file.Connections[name]
+		if conn := reg.conn(name); conn != nil {
+			if fresh, err := prepareConfig(cfg); err == nil && reflect.DeepEqual(&fresh, conn.config) {
+				continue
+			}
+		}
+		if err := openWithConfig(cfg, fileSource(path, name), true); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	for _, name := range file.cacheNames() {
+		cfg := file.Caches[name]
+		if entry := reg.cache(name); entry != nil && *entry.config == *cfg {
+			continue
+		}
+		if reg.conn(name) == nil {
+			if err := Open(name); err != nil {
+				errs = append(errs, err)
+				continue
+			}
+		}
+		if err := enableCache(name, cfg); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Returns the modification time of the file loaded by [LoadConfigFile], or zero if there is no such file.
+func configFileModTime() time.Time {
+	path := reg.configFilePath()
+	if path == "" {
+		return time.Time{}
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}
+	}
+	return info.ModTime()
+}
+
+// The [redis.Hook] to count the in-flight commands of a connection, so that it can be drained before closing.
+// It must be the first hook, so that it covers the others.
+type inflightHook struct {
+	count *atomic.Int64
+}
+
+func (h inflightHook) BeforeProcess(ctx context.Context, cmd redis.Cmder) (context.Context, error) {
+	h.count.Add(1)
+	return ctx, nil
+}
+
+func (h inflightHook) AfterProcess(ctx context.Context, cmd redis.Cmder) error {
+	h.count.Add(-1)
+	return nil
+}
+
+func (h inflightHook) BeforeProcessPipeline(ctx context.Context, cmds []redis.Cmder) (context.Context, error) {
+	h.count.Add(1)
+	return ctx, nil
+}
+
+func (h inflightHook) AfterProcessPipeline(ctx context.Context, cmds []redis.Cmder) error {
+	h.count.Add(-1)
+	return nil
+}
+
+// Wait until the in-flight commands of the connection finish, or ctx is done.
+// Returns the number of commands still in flight.
+func (conn *connection) drain(ctx context.Context) int64 {
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		n := conn.inflight.Load()
+		if n <= 0 {
+			return 0
+		}
+		select {
+		case <-ctx.Done():
+			return n
+		case <-ticker.C:
+		}
+	}
+}
+
+// Keep the connection open for the grace period of [Config].DrainTimeout, since the callers may still hold its client
+// which can not be counted until they run a command. Then drain it within [Config].DrainTimeout and close it.
+// It returns early if the connection is closed meanwhile, e.g. by [CloseAll].
+func (conn *connection) drainAndClose() error {
+	grace := time.NewTimer(conn.config.DrainTimeout)
+	defer grace.Stop()
+	select {
+	case <-grace.C:
+	case <-conn.closed:
+		return conn.close()
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), conn.config.DrainTimeout)
+	defer cancel()
+	if n := conn.drain(ctx); n > 0 {
+		goutils.Warnf("redis[%s]: drain timeout, closing the client with %d in-flight commands", conn.config.ConnectionName, n)
+	}
+	return conn.close()
+}
@@ -0,0 +1,187 @@
+package goredis_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"syscall"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/hecigo/goredis"
+	"github.com/hecigo/goutils"
+	. "gopkg.in/check.v1"
+)
+
+type ReloadSuite struct{}
+
+var _ = Suite(&ReloadSuite{})
+
+func (s *ReloadSuite) SetUpSuite(c *C) {
+	fmt.Println("SetUpSuite > ReloadSuite")
+	goutils.QuickLoad()
+}
+
+func (s *ReloadSuite) TearDownTest(c *C) {
+	goredis.Close("reload", "reload2")
+	os.Unsetenv("REDISRELOAD_KEY_PREFIX")
+	os.Unsetenv("REDISRELOAD_POOL_SIZE")
+}
+
+// Test the connection is rebuilt from fresh env, and the old client is closed after the grace period
+func (s *ReloadSuite) TestReload(c *C) {
+	os.Setenv("REDISRELOAD_KEY_PREFIX", "test-reload")
+	os.Setenv("REDISRELOAD_POOL_SIZE", "5")
+	os.Setenv("REDISRELOAD_DRAIN_TIMEOUT", "200ms")
+	defer os.Unsetenv("REDISRELOAD_DRAIN_TIMEOUT")
+	c.Assert(goredis.Open("reload"), IsNil)
+
+	ctx := reloadCtx()
+	old := goredis.Client(ctx)
+	os.Setenv("REDISRELOAD_POOL_SIZE", "7")
+	c.Assert(goredis.Reload("reload"), IsNil)
+
+	c.Assert(goredis.GetConfig(ctx).PoolSize, Equals, 7)
+	c.Assert(goredis.Client(ctx).Ping(ctx).Err(), IsNil)
+
+	// the client fetched before the reload still works during the grace period
+	c.Assert(old.Ping(ctx).Err(), IsNil)
+	time.Sleep(100 * time.Millisecond)
+	c.Assert(old.Ping(ctx).Err(), IsNil)
+	for i := 0; i < 100 && old.Ping(ctx).Err() == nil; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	c.Assert(old.Ping(ctx).Err(), Equals, redis.ErrClosed)
+
+	// a connection keeps its client if the fresh configuration is invalid
+	os.Setenv("REDISRELOAD_POOL_SIZE", "-1")
+	os.Setenv("REDISRELOAD_MIN_IDLE_CONNS", "-1")
+	defer os.Unsetenv("REDISRELOAD_MIN_IDLE_CONNS")
+	c.Assert(goredis.Reload("reload"), ErrorMatches, ".*min idle conns must not be negative.*")
+	c.Assert(goredis.GetConfig(ctx).PoolSize, Equals, 7)
+
+	c.Assert(goredis.Reload("reload_missing"), ErrorMatches, ".*reload_missing.*")
+}
+
+// Test the in-flight commands of the old client finish before it is closed
+func (s *ReloadSuite) TestDrain(c *C) {
+	c.Assert(goredis.OpenWithConfig(&goredis.Config{ConnectionName: "reload", KeyPrefix: "test-reload"}), IsNil)
+
+	ctx := reloadCtx()
+	done := make(chan error)
+	go func() {
+		// blocks until the timeout, the key never exists
+		done <- goredis.Client(ctx).BLPop(ctx, time.Second, "test-reload.test_reload_never").Err()
+	}()
+	time.Sleep(50 * time.Millisecond)
+
+	// the reload does not wait for the old client
+	start := time.Now()
+	c.Assert(goredis.Reload("reload"), IsNil)
+	c.Assert(time.Since(start) < 500*time.Millisecond, Equals, true)
+	c.Assert(<-done, Equals, redis.Nil)
+}
+
+// Test the cache is re-pointed at the new client
+func (s *ReloadSuite) TestCache(c *C) {
+	os.Setenv("REDISRELOAD_KEY_PREFIX", "test-reload")
+	c.Assert(goredis.EnableCache("reload"), IsNil)
+	c.Assert(goredis.Reload("reload"), IsNil)
+
+	ctx := reloadCtx()
+	c.Assert(goredis.SetCache(ctx, "test_reload_cache", "value"), IsNil)
+	var v string
+	c.Assert(goredis.GetCache(ctx, "test_reload_cache", &v), IsNil)
+	c.Assert(v, Equals, "value")
+}
+
+// Test SIGHUP and the change of the configuration file trigger reloads
+func (s *ReloadSuite) TestWatch(c *C) {
+	path := writeConfigFile(c, "redis.yaml", "connections:\n  reload:\n    key_prefix: test-reload\n    pool_size: 5\n")
+	c.Assert(goredis.LoadConfigFile(path), IsNil)
+
+	ctx, cancel := context.WithCancel(reloadCtx())
+	defer cancel()
+	goredis.WatchReload(ctx, 10*time.Millisecond)
+
+	// the file is rewritten
+	c.Assert(os.WriteFile(path, []byte("connections:\n  reload:\n    key_prefix: test-reload\n    pool_size: 6\n"), 0600), IsNil)
+	future := time.Now().Add(time.Minute)
+	c.Assert(os.Chtimes(path, future, future), IsNil)
+	waitReloaded(c, 6)
+
+	// env overrides the file on SIGHUP
+	os.Setenv("REDISRELOAD_POOL_SIZE", "8")
+	process, err := os.FindProcess(os.Getpid())
+	c.Assert(err, IsNil)
+	c.Assert(process.Signal(syscall.SIGHUP), IsNil)
+	waitReloaded(c, 8)
+}
+
+// Test a change of the configuration file reopens only the connections and the caches which changed
+func (s *ReloadSuite) TestWatchChanges(c *C) {
+	content := `
+connections:
+  reload:
+    key_prefix: test-reload
+    pool_size: %d
+  reload2:
+    key_prefix: test-reload2
+caches:
+  reload:
+  reload2:
+    ttl: %s
+`
+	path := writeConfigFile(c, "redis.yaml", fmt.Sprintf(content, 5, "1m"))
+	c.Assert(goredis.LoadConfigFile(path), IsNil)
+
+	ctx, cancel := context.WithCancel(reloadCtx())
+	defer cancel()
+	ctx2 := goredis.WithConnection(context.Background(), "reload2")
+	client2 := goredis.Client(ctx2)
+	cache2 := goredis.Cache(ctx2)
+
+	// the value is kept by the local cache only
+	c.Assert(goredis.SetCache(ctx, "test_reload_local", "value"), IsNil)
+	c.Assert(goredis.Client(ctx).Del(ctx, "test_reload_local").Err(), IsNil)
+
+	goredis.WatchReload(ctx, 10*time.Millisecond)
+	rewrite := func(poolSize int, ttl string) {
+		c.Assert(os.WriteFile(path, []byte(fmt.Sprintf(content, poolSize, ttl)), 0600), IsNil)
+		future := time.Now().Add(time.Duration(poolSize) * time.Minute)
+		c.Assert(os.Chtimes(path, future, future), IsNil)
+	}
+
+	// only `reload` changes, its local cache is kept
+	rewrite(6, "1m")
+	waitReloaded(c, 6)
+	c.Assert(goredis.Client(ctx2) == client2, Equals, true)
+	c.Assert(goredis.Cache(ctx2) == cache2, Equals, true)
+	var v string
+	c.Assert(goredis.GetCache(ctx, "test_reload_local", &v), IsNil)
+	c.Assert(v, Equals, "value")
+
+	// only the cache of `reload2` changes
+	rewrite(7, "2m")
+	waitReloaded(c, 7)
+	for i := 0; i < 100 && goredis.Describe().Caches["reload2"].DefaultTTL != 2*time.Minute; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	c.Assert(goredis.Describe().Caches["reload2"].DefaultTTL, Equals, 2*time.Minute)
+	c.Assert(goredis.Client(ctx2) == client2, Equals, true)
+}
+
+// Poll until the connection is reloaded with the pool size
+func waitReloaded(c *C, poolSize int) {
+	for i := 0; i < 100; i++ {
+		if goredis.GetConfig(reloadCtx()).PoolSize == poolSize {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	c.Fatalf("the connection is not reloaded with pool size %d", poolSize)
+}
+
+func reloadCtx() context.Context {
+	return context.WithValue(context.Background(), goutils.CtxKey_ConnName, "reload")
+}
@@ -0,0 +1,172 @@
+package goredis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// The node to serve the read commands of a call, set by [CtxKey_ReadPreference] in context.
+type ReadPreference string
+
+const (
+	ReadPreference_Default ReadPreference   = ""        // replicas if [Config].ReadOnly is enabled, otherwise the master
+	ReadPreference_Master  ReadPreference   = "master"  // always the master, e.g. to read your own writes
+	ReadPreference_Replica ReadPreference   = "replica" // replicas if the connection has any, otherwise the master
+	CtxKey_ReadPreference  ctxKeyType_Redis = "redis_read_preference"
+)
+
+// Returns true if the read commands of the call should be served by replicas.
+func useReplica(ctx context.Context, readOnly bool) bool {
+	if ctx == nil {
+		return readOnly
+	}
+
+	var pref ReadPreference
+	switch v := ctx.Value(CtxKey_ReadPreference).(type) {
+	case ReadPreference:
+		pref = v
+	case string:
+		pref = ReadPreference(v)
+	}
+
+	switch pref {
+	case ReadPreference_Master:
+		return false
+	case ReadPreference_Replica:
+		return true
+	default:
+		return readOnly
+	}
+}
+
+// Returns the client to serve read commands, following [CtxKey_ReadPreference] in context.
+// Writes must always use the client of the connection, which is connected to the master.
+func (conn *connection) reader(ctx context.Context) redis.UniversalClient {
+	if conn.replica != nil && useReplica(ctx, conn.config.ReadOnly) {
+		return conn.replica
+	}
+	return conn.client
+}
+
+// Run the read commands in a pipeline. Transactions are always served by the master,
+// so a plain pipeline is used when reading from replicas.
+func (conn *connection) readPipelined(ctx context.Context, fn func(redis.Pipeliner) error) ([]redis.Cmder, error) {
+	client := conn.reader(ctx)
+	if client == conn.client {
+		return client.TxPipelined(ctx, fn)
+	}
+	return client.Pipelined(ctx, fn)
+}
+
+// Validate the replica settings fit the mode.
+func (cfg *Config) validateReplicas() error {
+	mode := cfg.resolvedMode()
+	if len(cfg.ReplicaAddresses) > 0 && mode != Mode_Standalone {
+		return fmt.Errorf("mode `%s` discovers replicas itself, replica addresses are only supported by mode `%s`", mode, Mode_Standalone)
+	}
+	if !cfg.ReadOnly {
+		return nil
+	}
+
+	switch mode {
+	case Mode_Ring:
+		return fmt.Errorf("mode `%s` does not support read replicas", mode)
+	case Mode_Standalone:
+		if len(cfg.ReplicaAddresses) == 0 {
+			return errors.New("read only requires replica addresses in standalone mode")
+		}
+	}
+	return nil
+}
+
+// Create the client to serve read commands from replicas, or nil if [Config].ReadOnly is disabled.
+// go-redis routes only the read-only commands to replicas, the others still go to the master.
+func (cfg *Config) newReplicaClient(opts *redis.UniversalOptions) redis.UniversalClient {
+	if !cfg.ReadOnly {
+		return nil
+	}
+
+	switch cfg.resolvedMode() {
+	case Mode_Failover:
+		failoverOpts := opts.Failover()
+		if !cfg.RouteByLatency && !cfg.RouteRandomly {
+			failoverOpts.SlaveOnly = true
+			return redis.NewFailoverClient(failoverOpts)
+		}
+
+		// the failover cluster client ignores DB, select it on every new connection
+		failoverOpts.RouteByLatency = cfg.RouteByLatency
+		failoverOpts.RouteRandomly = cfg.RouteRandomly
+		failoverOpts.OnConnect = cfg.onConnect(cfg.DB)
+		return redis.NewFailoverClusterClient(failoverOpts)
+
+	case Mode_Cluster:
+		clusterOpts := opts.Cluster()
+		clusterOpts.ReadOnly = true
+		clusterOpts.RouteByLatency = cfg.RouteByLatency
+		clusterOpts.RouteRandomly = cfg.RouteRandomly
+		return redis.NewClusterClient(clusterOpts)
+
+	default:
+		// a standalone master with its replicas is served as a single-shard cluster,
+		// the master is the first node and go-redis picks replicas for read-only commands
+		nodes := []redis.ClusterNode{{Addr: cfg.Addresses[0]}}
+		for _, addr := range cfg.ReplicaAddresses {
+			nodes = append(nodes, redis.ClusterNode{Addr: addr})
+		}
+
+		clusterOpts := opts.Cluster()
+		clusterOpts.ClusterSlots = func(ctx context.Context) ([]redis.ClusterSlot, error) {
+			return []redis.ClusterSlot{{Start: 0, End: 16383, Nodes: nodes}}, nil
+		}
+		clusterOpts.ReadOnly = true
+		clusterOpts.RouteByLatency = cfg.RouteByLatency
+		clusterOpts.RouteRandomly = cfg.RouteRandomly
+		clusterOpts.OnConnect = cfg.onConnect(cfg.DB)
+		return redis.NewClusterClient(clusterOpts)
+	}
+}
+
+// Wrap the OnConnect hook to select the DB, for the clients which do not support DB by options.
+func selectDB(db int, onConnect func(ctx context.Context, cn *redis.Conn) error) func(ctx context.Context, cn *redis.Conn) error {
+	if db == 0 {
+		return onConnect
+	}
+
+	return func(ctx context.Context, cn *redis.Conn) error {
+		if err := cn.Select(ctx, db).Err(); err != nil {
+			return err
+		}
+		if onConnect != nil {
+			return onConnect(ctx, cn)
+		}
+		return nil
+	}
+}
+
+// Parse the replica addresses, which can be either `host:port` or URLs. Only the address of the URLs is used,
+// the replicas share the other options with the master.
+func parseReplicaURL(addresses ...string) ([]string, error) {
+	var result []string
+	for _, addr := range addresses {
+		addr = strings.TrimSpace(addr)
+		if addr == "" {
+			continue
+		}
+		if !strings.Contains(addr, "://") {
+			result = append(result, addr)
+			continue
+		}
+
+		opt, err := redis.ParseURL(addr)
+		if err != nil {
+			return nil, redactURLError(err)
+		}
+		result = append(result, opt.Addr)
+	}
+	return result, nil
+}
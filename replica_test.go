@@ -0,0 +1,158 @@
+package goredis_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/hecigo/goredis"
+	"github.com/hecigo/goutils"
+	. "gopkg.in/check.v1"
+)
+
+// Requires a local master at localhost:6379 and its replica at localhost:6381, served in-process,
+// see runOffline.
+type ReplicaSuite struct{}
+
+var _ = Suite(&ReplicaSuite{})
+
+func (s *ReplicaSuite) SetUpSuite(c *C) {
+	fmt.Println("SetUpSuite > ReplicaSuite")
+	goutils.QuickLoad()
+}
+
+func (s *ReplicaSuite) TearDownTest(c *C) {
+	goredis.Close("replica")
+	for _, k := range []string{"URL", "REPLICA_URL", "READ_ONLY", "ROUTE_BY_LATENCY", "ROUTE_RANDOMLY", "MODE"} {
+		os.Unsetenv("REDISREPLICA_" + k)
+	}
+}
+
+// Test the replica options are loaded from env
+func (s *ReplicaSuite) TestConfig(c *C) {
+	os.Setenv("REDISREPLICA_URL", "localhost:6379")
+	os.Setenv("REDISREPLICA_REPLICA_URL", "redis://localhost:6381;localhost:6382")
+	os.Setenv("REDISREPLICA_ROUTE_RANDOMLY", "true")
+	c.Assert(goredis.Open("replica"), IsNil)
+
+	cfg := goredis.GetConfig(replicaCtx())
+	c.Assert(cfg.ReplicaAddresses, DeepEquals, []string{"localhost:6381", "localhost:6382"})
+	c.Assert(cfg.RouteRandomly, Equals, true)
+	c.Assert(cfg.ReadOnly, Equals, true)
+}
+
+// Test the replica options are validated against the mode
+func (s *ReplicaSuite) TestValidation(c *C) {
+	os.Setenv("REDISREPLICA_READ_ONLY", "true")
+	c.Assert(goredis.Open("replica"), ErrorMatches, ".*requires replica addresses.*")
+
+	os.Setenv("REDISREPLICA_MODE", "ring")
+	c.Assert(goredis.Open("replica"), ErrorMatches, ".*does not support read replicas.*")
+
+	os.Setenv("REDISREPLICA_MODE", "cluster")
+	os.Setenv("REDISREPLICA_REPLICA_URL", "localhost:6381")
+	c.Assert(goredis.Open("replica"), ErrorMatches, ".*replica addresses are only supported.*")
+}
+
+// Test reads go to the replica while writes stay on the master
+func (s *ReplicaSuite) TestReadFromReplica(c *C) {
+	c.Assert(goredis.OpenWithConfig(&goredis.Config{
+		ConnectionName:   "replica",
+		Addresses:        []string{"localhost:6379"},
+		ReplicaAddresses: []string{"localhost:6381"},
+		ReadOnly:         true,
+		KeyPrefix:        "test-replica",
+	}), IsNil)
+
+	// writes are served by the master even if the call prefers replicas, a replica would reject them
+	ctx := context.WithValue(replicaCtx(), goredis.CtxKey_ReadPreference, goredis.ReadPreference_Replica)
+	c.Assert(goredis.Set(ctx, "test_replica", "value"), IsNil)
+	c.Assert(goredis.MSet(ctx, map[string]interface{}{"test_replica_1": "1", "test_replica_2": "2"}), IsNil)
+	board := goredis.GetRankingBoard(ctx, "test_replica_ranking")
+	c.Assert(board.UpsertMulti(map[string]float64{"member1": 1, "member2": 2}), IsNil)
+
+	// the writes land on the master only
+	stored, err := offlineMaster.Get("test-replica.test_replica")
+	c.Assert(err, IsNil)
+	c.Assert(stored, Equals, "value")
+	c.Assert(offlineMaster.Exists("test-replica.test_replica_1"), Equals, true)
+	c.Assert(offlineMaster.Exists(board.Id), Equals, true)
+	for _, key := range []string{"test-replica.test_replica", "test-replica.test_replica_1", "test-replica.test_replica_2", board.Id} {
+		c.Assert(offlineReplica.Exists(key), Equals, false)
+	}
+
+	// the replica does not replicate the master, then it is seeded with other values to tell that the reads come from it
+	c.Assert(offlineReplica.Set("test-replica.test_replica", "replica"), IsNil)
+	c.Assert(offlineReplica.Set("test-replica.test_replica_1", "10"), IsNil)
+	c.Assert(offlineReplica.Set("test-replica.test_replica_2", "20"), IsNil)
+	_, err = offlineReplica.ZAdd(board.Id, 10, "member1")
+	c.Assert(err, IsNil)
+	_, err = offlineReplica.ZAdd(board.Id, 20, "member2")
+	c.Assert(err, IsNil)
+
+	for _, ctx := range []context.Context{replicaCtx(), ctx} {
+		v, err := goredis.Get[string](ctx, "test_replica")
+		c.Assert(err, IsNil)
+		c.Assert(v, Equals, "replica")
+		one, two := 10, 20
+		m, err := goredis.Get[int](ctx, "test_replica_1", "test_replica_2")
+		c.Assert(err, IsNil)
+		c.Assert(m, DeepEquals, map[string]*int{"test_replica_1": &one, "test_replica_2": &two})
+
+		board := goredis.GetRankingBoard(ctx, "test_replica_ranking")
+		top, err := board.Top(2)
+		c.Assert(err, IsNil)
+		c.Assert(top, DeepEquals, map[string]float64{"member1": 10, "member2": 20})
+		scores, err := board.Scores("member1", "member2")
+		c.Assert(err, IsNil)
+		c.Assert(scores, DeepEquals, map[string]float64{"member1": 10, "member2": 20})
+	}
+
+	// the master is still read on demand
+	master := context.WithValue(replicaCtx(), goredis.CtxKey_ReadPreference, goredis.ReadPreference_Master)
+	v, err := goredis.Get[string](master, "test_replica")
+	c.Assert(err, IsNil)
+	c.Assert(v, Equals, "value")
+	top, err := goredis.GetRankingBoard(master, "test_replica_ranking").Top(2)
+	c.Assert(err, IsNil)
+	c.Assert(top, DeepEquals, map[string]float64{"member1": 1, "member2": 2})
+}
+
+// Test the cache reads from the replica while the writes land on the master
+func (s *ReplicaSuite) TestCache(c *C) {
+	os.Setenv("REDISREPLICA_REPLICA_URL", "localhost:6381")
+	os.Setenv("REDISREPLICA_READ_ONLY", "true")
+	c.Assert(goredis.EnableCache("replica"), IsNil)
+
+	replica := context.WithValue(replicaCtx(), goredis.CtxKey_ReadPreference, goredis.ReadPreference_Replica)
+	master := context.WithValue(replicaCtx(), goredis.CtxKey_ReadPreference, goredis.ReadPreference_Master)
+	c.Assert(goredis.SetCache(replica, "test_replica_cache", "value", time.Minute), IsNil)
+	stored, err := offlineMaster.Get("test_replica_cache")
+	c.Assert(err, IsNil)
+	c.Assert(stored, Equals, `"value"`)
+	c.Assert(offlineReplica.Exists("test_replica_cache"), Equals, false)
+
+	// the keys are seeded with other values on each server, and read once since the local cache keeps them
+	for _, key := range []string{"test_replica_cache_1", "test_replica_cache_2", "test_replica_cache_3"} {
+		c.Assert(offlineMaster.Set(key, `"master"`), IsNil)
+		c.Assert(offlineReplica.Set(key, `"replica"`), IsNil)
+	}
+	for _, read := range []struct {
+		ctx  context.Context
+		key  string
+		want string
+	}{
+		{replicaCtx(), "test_replica_cache_1", "replica"}, // READ_ONLY reads from the replica by default
+		{replica, "test_replica_cache_2", "replica"},
+		{master, "test_replica_cache_3", "master"},
+	} {
+		var v string
+		c.Assert(goredis.GetCache(read.ctx, read.key, &v), IsNil)
+		c.Assert(v, Equals, read.want, Commentf(read.key))
+	}
+}
+
+func replicaCtx() context.Context {
+	return context.WithValue(context.Background(), goutils.CtxKey_ConnName, "replica")
+}
@@ -0,0 +1,275 @@
+package goredis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/go-redis/cache/v8"
+	"github.com/hecigo/goutils"
+)
+
+// The context key of the calls mirrored to a shadow connection, so that they are not mirrored again.
+type ctxKeyType_Shadow struct{}
+
+// Mirrors the writes of a connection to [Config].ShadowConnection, and compares the reads if
+// [Config].ShadowCompareReads is enabled. The jobs run one by one in the background, in the order of the calls,
+// so that the shadow connection receives the writes in the same order as the connection.
+// The jobs are dropped if the queue is full, a slow shadow connection never blocks the calls.
+//
+// A reloaded connection takes over the shadow of the old one if it is configured the same, so that the queued jobs
+// are not lost and keep their order. The shadow stops when the last connection sharing it is closed.
+type shadow struct {
+	connName string
+	target   string
+	compare  bool
+
+	jobs chan func()
+	done chan struct{}
+
+	mu      sync.Mutex
+	refs    int // the connections sharing the shadow
+	stopped bool
+}
+
+// Returns the shadow of the connection and starts its worker, or nil if it has no shadow connection.
+// The shadow of current, the connection being replaced, is shared if it has the same configuration.
+func (cfg *Config) newShadow(current *connection) *shadow {
+	if cfg.ShadowConnection == "" {
+		return nil
+	}
+	if current != nil && current.shadow.sameConfig(cfg) && current.shadow.acquire() {
+		return current.shadow
+	}
+
+	s := &shadow{
+		connName: cfg.ConnectionName,
+		target:   cfg.ShadowConnection,
+		compare:  cfg.ShadowCompareReads,
+		jobs:     make(chan func(), cfg.ShadowQueueSize),
+		done:     make(chan struct{}),
+		refs:     1,
+	}
+	go s.run()
+	return s
+}
+
+// Returns true if the shadow is configured as cfg.
+func (s *shadow) sameConfig(cfg *Config) bool {
+	return s != nil && s.connName == cfg.ConnectionName && s.target == cfg.ShadowConnection &&
+		s.compare == cfg.ShadowCompareReads && cap(s.jobs) == cfg.ShadowQueueSize
+}
+
+// Share the shadow with one more connection, or returns false if it is already stopped.
+func (s *shadow) acquire() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.stopped {
+		return false
+	}
+	s.refs++
+	return true
+}
+
+// Run the jobs until the shadow is stopped, then run the queued jobs before returning.
+func (s *shadow) run() {
+	for {
+		select {
+		case job := <-s.jobs:
+			s.exec(job)
+		case <-s.done:
+			for {
+				select {
+				case job := <-s.jobs:
+					s.exec(job)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+// Run a job, a panic of the shadow path must not crash the process.
+func (s *shadow) exec(job func()) {
+	defer func() {
+		if r := recover(); r != nil {
+			s.report("error")
+			goutils.Errorf("redis[%s]: shadow `%s` panic: %v", s.connName, s.target, r)
+		}
+	}()
+	job()
+}
+
+// Release the shadow when a connection sharing it is closed. The worker stops with the last connection,
+// the queued jobs still run.
+func (s *shadow) stop() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.refs--; s.refs > 0 || s.stopped {
+		return
+	}
+	s.stopped = true
+	close(s.done)
+}
+
+// Queue the job, or drop it if the queue is full or the shadow is stopped.
+func (s *shadow) enqueue(job func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.stopped {
+		s.report("dropped")
+		return
+	}
+	select {
+	case s.jobs <- job:
+	default:
+		s.report("dropped")
+	}
+}
+
+// Returns the context of the shadow calls. It keeps the values of ctx, such as the data type and the key prefix,
+// but not its deadline and cancellation, because the shadow calls outlive the call.
+func (s *shadow) context(ctx context.Context) context.Context {
+	ctx = context.WithValue(detachedCtx{parent: ctx}, ctxKeyType_Shadow{}, s.connName)
+	return WithConnection(ctx, s.target)
+}
+
+// Mirror a succeeded write to the shadow connection.
+func (s *shadow) mirror(ctx context.Context, op string, fn func(ctx context.Context) error) {
+	sctx := s.context(ctx)
+	s.enqueue(func() {
+		if err := fn(sctx); err != nil {
+			s.report("error")
+			goutils.Warnf("redis[%s]: shadow `%s` %s: %s", s.connName, s.target, op, err)
+			return
+		}
+		s.report("mirrored")
+	})
+}
+
+// Compare a succeeded read with the shadow connection, the mismatches are logged and counted.
+func (s *shadow) compareRead(ctx context.Context, op string, v interface{}, fn func(ctx context.Context) (interface{}, error)) {
+	if !s.compare {
+		return
+	}
+
+	sctx := s.context(ctx)
+	s.enqueue(func() {
+		sv, err := fn(sctx)
+		if err != nil {
+			s.report("error")
+			goutils.Warnf("redis[%s]: shadow `%s` %s: %s", s.connName, s.target, op, err)
+			return
+		}
+		if !reflect.DeepEqual(v, sv) {
+			s.report("mismatched")
+			goutils.Warnf("redis[%s]: shadow `%s` %s mismatched: %v != %v", s.connName, s.target, op, describeValue(v), describeValue(sv))
+			return
+		}
+		s.report("matched")
+	})
+}
+
+// Count the result of a shadow job.
+func (s *shadow) report(result string) {
+	Metrics()
+	metrics.shadow.WithLabelValues(s.connName, s.target, result).Inc()
+}
+
+// Returns the shadow of the connection of ctx, or nil if it has none or the call is already mirrored.
+func shadowOf(ctx context.Context, defaultName string) *shadow {
+	if ctx == nil || ctx.Value(ctxKeyType_Shadow{}) != nil {
+		return nil
+	}
+	name, err := ctxConnName(defaultName, ctx)
+	if err != nil {
+		return nil
+	}
+	conn := reg.conn(name)
+	if conn == nil {
+		return nil
+	}
+	return conn.shadow
+}
+
+// Run the write with failover, then mirror it to the shadow connection if it succeeded.
+func withShadow(ctx context.Context, defaultName string, op string, fn func(ctx context.Context) error) error {
+	err := withFailover(ctx, defaultName, true, fn)
+	if err == nil {
+		if s := shadowOf(ctx, defaultName); s != nil {
+			s.mirror(ctx, op, fn)
+		}
+	}
+	return err
+}
+
+// Similar to [withShadow], but the write returns a value, e.g. the new score of [RankingBoard.IncrBy].
+func withShadowValue[T any](ctx context.Context, defaultName string, op string, fn func(ctx context.Context) (T, error)) (T, error) {
+	v, err := withFailoverValue(ctx, defaultName, true, fn)
+	if err == nil {
+		if s := shadowOf(ctx, defaultName); s != nil {
+			s.mirror(ctx, op, func(ctx context.Context) error {
+				_, err := fn(ctx)
+				return err
+			})
+		}
+	}
+	return v, err
+}
+
+// Run the read with failover, then compare it with the shadow connection if it succeeded.
+func withShadowRead[T any](ctx context.Context, defaultName string, op string, fn func(ctx context.Context) (T, error)) (T, error) {
+	v, err := withFailoverValue(ctx, defaultName, false, fn)
+	if err == nil {
+		if s := shadowOf(ctx, defaultName); s != nil {
+			s.compareRead(ctx, op, v, func(ctx context.Context) (interface{}, error) {
+				return fn(ctx)
+			})
+		}
+	}
+	return v, err
+}
+
+// Compare a succeeded read of [GetCache] with the cache of the shadow connection.
+// A miss of the shadow cache is a mismatch, not an error.
+func (s *shadow) compareCache(ctx context.Context, key string, value interface{}) {
+	elem := reflect.TypeOf(value).Elem()
+	s.compareRead(ctx, "GetCache "+key, reflect.ValueOf(value).Elem().Interface(), func(ctx context.Context) (interface{}, error) {
+		ptr := reflect.New(elem)
+		err := getCache(ctx, key, ptr.Interface())
+		if errors.Is(err, cache.ErrCacheMiss) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		return ptr.Elem().Interface(), nil
+	})
+}
+
+// Format a compared value for logs, the pointers are dereferenced.
+func describeValue(v interface{}) string {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Pointer && !rv.IsNil() {
+		return fmt.Sprintf("%+v", rv.Elem().Interface())
+	}
+	return fmt.Sprintf("%+v", v)
+}
+
+// A context which keeps the values of its parent without its deadline and cancellation,
+// the same as context.WithoutCancel of Go 1.21.
+type detachedCtx struct {
+	parent context.Context
+}
+
+func (c detachedCtx) Deadline() (time.Time, bool)       { return time.Time{}, false }
+func (c detachedCtx) Done() <-chan struct{}             { return nil }
+func (c detachedCtx) Err() error                        { return nil }
+func (c detachedCtx) Value(key interface{}) interface{} { return c.parent.Value(key) }
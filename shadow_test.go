@@ -0,0 +1,217 @@
+package goredis_test
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/hecigo/goredis"
+	"github.com/hecigo/goutils"
+	"github.com/prometheus/client_golang/prometheus"
+	. "gopkg.in/check.v1"
+)
+
+// The writes of the connection `shadow` are mirrored to `shadow2`.
+type ShadowSuite struct {
+	registry *prometheus.Registry
+}
+
+var _ = Suite(&ShadowSuite{})
+
+func (s *ShadowSuite) SetUpSuite(c *C) {
+	fmt.Println("SetUpSuite > ShadowSuite")
+	goutils.QuickLoad()
+
+	s.registry = prometheus.NewRegistry()
+	c.Assert(s.registry.Register(goredis.Metrics()), IsNil)
+}
+
+func (s *ShadowSuite) TearDownTest(c *C) {
+	goredis.Close("shadow", "shadow2")
+}
+
+// Test the writes are mirrored to the shadow connection
+func (s *ShadowSuite) TestMirror(c *C) {
+	s.load(c, "localhost:6379")
+	ctx := goredis.WithConnection(context.Background(), "shadow")
+	shadow := goredis.WithConnection(context.Background(), "shadow2")
+	s.clean(c, "test_shadow", "test_shadow2", "test_shadow_zset", "test_shadow_cache")
+
+	c.Assert(goredis.Set(ctx, "test_shadow", "value"), IsNil)
+	c.Assert(goredis.MSet(ctx, map[string]interface{}{"test_shadow2": "value2"}), IsNil)
+	c.Assert(goredis.GetRankingBoard(ctx, "test_shadow_zset").UpsertMulti(map[string]float64{"a": 1, "b": 2}), IsNil)
+	_, err := goredis.GetRankingBoard(ctx, "test_shadow_zset").IncrBy("a", 2)
+	c.Assert(err, IsNil)
+	c.Assert(goredis.SetCache(ctx, "test_shadow_cache", "cached"), IsNil)
+
+	eventually(c, func() bool {
+		v, err := goredis.Get[string](shadow, "test_shadow2")
+		return err == nil && v == "value2"
+	})
+	v, err := goredis.Get[string](shadow, "test_shadow")
+	c.Assert(err, IsNil)
+	c.Assert(v, Equals, "value")
+
+	eventually(c, func() bool {
+		score, err := goredis.GetRankingBoard(shadow, "test_shadow_zset").Score("a")
+		return err == nil && score == 3
+	})
+
+	eventually(c, func() bool {
+		var cached string
+		return goredis.GetCache(shadow, "test_shadow_cache", &cached) == nil && cached == "cached"
+	})
+}
+
+// Test the reads are compared with the shadow connection, and the mismatches are counted
+func (s *ShadowSuite) TestCompareReads(c *C) {
+	s.load(c, "localhost:6379")
+	ctx := goredis.WithConnection(context.Background(), "shadow")
+	shadow := goredis.WithConnection(context.Background(), "shadow2")
+	s.clean(c, "test_shadow_read")
+	before := (&MetricsSuite{registry: s.registry}).gather(c)
+
+	c.Assert(goredis.Set(ctx, "test_shadow_read", "value"), IsNil)
+	eventually(c, func() bool {
+		v, err := goredis.Get[string](shadow, "test_shadow_read")
+		return err == nil && v != nil
+	})
+
+	key := `goredis_shadow_total{connection="shadow",result="%s",shadow="shadow2"}`
+	counted := func(result string, n float64) func() bool {
+		return func() bool {
+			after := (&MetricsSuite{registry: s.registry}).gather(c)
+			return after[fmt.Sprintf(key, result)]-before[fmt.Sprintf(key, result)] == n
+		}
+	}
+
+	v, err := goredis.Get[string](ctx, "test_shadow_read")
+	c.Assert(err, IsNil)
+	c.Assert(v, Equals, "value")
+	eventually(c, counted("matched", 1))
+
+	// the writes of the shadow connection itself are not mirrored
+	c.Assert(goredis.Set(shadow, "test_shadow_read", "other"), IsNil)
+	v, err = goredis.Get[string](ctx, "test_shadow_read")
+	c.Assert(err, IsNil)
+	c.Assert(v, Equals, "value")
+	eventually(c, counted("mismatched", 1))
+}
+
+// Test a failing shadow connection does not affect the connection
+func (s *ShadowSuite) TestUnavailable(c *C) {
+	s.load(c, "localhost:1")
+	ctx := goredis.WithConnection(context.Background(), "shadow")
+	before := (&MetricsSuite{registry: s.registry}).gather(c)
+
+	c.Assert(goredis.Set(ctx, "test_shadow_down", "value"), IsNil)
+	v, err := goredis.Get[string](ctx, "test_shadow_down")
+	c.Assert(err, IsNil)
+	c.Assert(v, Equals, "value")
+
+	key := `goredis_shadow_total{connection="shadow",result="error",shadow="shadow2"}`
+	eventually(c, func() bool {
+		after := (&MetricsSuite{registry: s.registry}).gather(c)
+		return after[key]-before[key] == 2
+	})
+}
+
+// Test the queued writes are handed over to the reloaded connection, none is lost and they keep their order
+func (s *ShadowSuite) TestReload(c *C) {
+	// the shadow connection is slow, so that the writes are still queued when the connection is reloaded
+	c.Assert(goredis.OpenWithConfig(&goredis.Config{ConnectionName: "shadow2", KeyPrefix: "test-shadow2", Hooks: []redis.Hook{slowHook{}}}), IsNil)
+	c.Assert(goredis.OpenWithConfig(&goredis.Config{ConnectionName: "shadow", KeyPrefix: "test-shadow", ShadowConnection: "shadow2"}), IsNil)
+	ctx := goredis.WithConnection(context.Background(), "shadow")
+	shadow := goredis.WithConnection(context.Background(), "shadow2")
+	s.clean(c, "test_shadow_reload")
+	before := (&MetricsSuite{registry: s.registry}).gather(c)
+
+	for i := 0; i < 100; i++ {
+		c.Assert(goredis.Set(ctx, "test_shadow_reload", fmt.Sprintf("v%d", i)), IsNil)
+		if i%20 == 10 {
+			c.Assert(goredis.Reload("shadow"), IsNil)
+		}
+	}
+
+	key := `goredis_shadow_total{connection="shadow",result="%s",shadow="shadow2"}`
+	eventually(c, func() bool {
+		after := (&MetricsSuite{registry: s.registry}).gather(c)
+		return after[fmt.Sprintf(key, "mirrored")]-before[fmt.Sprintf(key, "mirrored")] == 100
+	})
+	after := (&MetricsSuite{registry: s.registry}).gather(c)
+	c.Assert(after[fmt.Sprintf(key, "dropped")]-before[fmt.Sprintf(key, "dropped")], Equals, 0.0)
+	v, err := goredis.Get[string](shadow, "test_shadow_reload")
+	c.Assert(err, IsNil)
+	c.Assert(v, Equals, "v99")
+}
+
+// Test a connection can not shadow itself
+func (s *ShadowSuite) TestValidation(c *C) {
+	err := goredis.OpenWithConfig(&goredis.Config{ConnectionName: "shadow", KeyPrefix: "test-shadow", ShadowConnection: "shadow", ShadowQueueSize: -1})
+	c.Assert(err, ErrorMatches, "(?s).*shadow connection must not be the connection itself.*shadow queue size must not be negative.*")
+}
+
+// Load the connections and their caches, the shadow connection is at addr
+func (s *ShadowSuite) load(c *C, addr string) {
+	path := writeConfigFile(c, "redis.yaml", fmt.Sprintf(`
+connections:
+  shadow:
+    key_prefix: test-shadow
+    shadow_connection: shadow2
+    shadow_compare_reads: true
+  shadow2:
+    addresses: [%s]
+    key_prefix: test-shadow2
+    max_retries: -1
+caches:
+  shadow:
+  shadow2:
+`, addr))
+	c.Assert(goredis.LoadConfigFile(path), IsNil)
+}
+
+// Delete the keys left by the previous runs from both connections, with and without the prefix as the cache keys are
+func (s *ShadowSuite) clean(c *C, keys ...string) {
+	for _, name := range []string{"shadow", "shadow2"} {
+		ctx := goredis.WithConnection(context.Background(), name)
+		kb, err := goredis.GetKeyBuilder(ctx)
+		c.Assert(err, IsNil)
+		all := append([]string{}, keys...)
+		for _, key := range keys {
+			all = append(all, kb.Key(key))
+		}
+		c.Assert(goredis.Client(ctx).Del(ctx, all...).Err(), IsNil)
+	}
+}
+
+// Delays every command by a millisecond.
+type slowHook struct{}
+
+func (slowHook) BeforeProcess(ctx context.Context, cmd redis.Cmder) (context.Context, error) {
+	time.Sleep(time.Millisecond)
+	return ctx, nil
+}
+
+func (slowHook) AfterProcess(ctx context.Context, cmd redis.Cmder) error {
+	return nil
+}
+
+func (slowHook) BeforeProcessPipeline(ctx context.Context, cmds []redis.Cmder) (context.Context, error) {
+	return ctx, nil
+}
+
+func (slowHook) AfterProcessPipeline(ctx context.Context, cmds []redis.Cmder) error {
+	return nil
+}
+
+// Wait until cond is true, the shadow jobs run in the background.
+func eventually(c *C, cond func() bool) {
+	for i := 0; i < 100; i++ {
+		if cond() {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	c.Fatal("the condition is not met in time")
+}
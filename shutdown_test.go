@@ -0,0 +1,86 @@
+package goredis_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/hecigo/goredis"
+	"github.com/hecigo/goutils"
+	. "gopkg.in/check.v1"
+)
+
+// CloseAll closes every connection, the other suites open their own connections in SetUpSuite.
+type ShutdownSuite struct{}
+
+var _ = Suite(&ShutdownSuite{})
+
+func (s *ShutdownSuite) SetUpSuite(c *C) {
+	fmt.Println("SetUpSuite > ShutdownSuite")
+	goutils.QuickLoad()
+}
+
+// Test every connection is closed after its in-flight commands, and the later calls fail cleanly
+func (s *ShutdownSuite) TestCloseAll(c *C) {
+	os.Setenv("REDISSHUTDOWN_KEY_PREFIX", "test-shutdown")
+	defer os.Unsetenv("REDISSHUTDOWN_KEY_PREFIX")
+	c.Assert(goredis.Open("shutdown"), IsNil)
+	c.Assert(goredis.EnableCache("shutdown"), IsNil)
+
+	ctx := context.WithValue(context.Background(), goutils.CtxKey_ConnName, "shutdown")
+	done := make(chan error)
+	go func() {
+		done <- goredis.Client(ctx).BLPop(ctx, time.Second, "test-shutdown.test_shutdown_never").Err()
+	}()
+	time.Sleep(50 * time.Millisecond)
+
+	c.Assert(goredis.CloseAll(context.Background()), IsNil)
+	c.Assert(<-done, Equals, redis.Nil)
+
+	_, err := goredis.ClientE(ctx)
+	c.Assert(err, Equals, goredis.ErrShutdown)
+	c.Assert(goredis.Client(ctx).Ping(ctx).Err(), Equals, redis.ErrClosed)
+	c.Assert(goredis.Set(ctx, "test_shutdown", "value"), Equals, goredis.ErrShutdown)
+	c.Assert(goredis.SetCache(ctx, "test_shutdown", "value"), Equals, goredis.ErrShutdown)
+	c.Assert(goredis.Reload("shutdown"), ErrorMatches, ".*shut down.*")
+}
+
+// Test the old client of a reloaded connection is drained before CloseAll returns
+func (s *ShutdownSuite) TestCloseReloaded(c *C) {
+	c.Assert(goredis.OpenWithConfig(&goredis.Config{ConnectionName: "shutdown", KeyPrefix: "test-shutdown"}), IsNil)
+
+	ctx := context.WithValue(context.Background(), goutils.CtxKey_ConnName, "shutdown")
+	done := make(chan error)
+	go func() {
+		done <- goredis.Client(ctx).BLPop(ctx, 500*time.Millisecond, "test-shutdown.test_shutdown_never").Err()
+	}()
+	time.Sleep(50 * time.Millisecond)
+	c.Assert(goredis.Reload("shutdown"), IsNil)
+
+	start := time.Now()
+	c.Assert(goredis.CloseAll(context.Background()), IsNil)
+	c.Assert(time.Since(start) > 300*time.Millisecond, Equals, true)
+	c.Assert(<-done, Equals, redis.Nil)
+}
+
+// Test the in-flight commands are abandoned at the deadline
+func (s *ShutdownSuite) TestDeadline(c *C) {
+	c.Assert(goredis.OpenWithConfig(&goredis.Config{ConnectionName: "shutdown", KeyPrefix: "test-shutdown"}), IsNil)
+
+	ctx := context.WithValue(context.Background(), goutils.CtxKey_ConnName, "shutdown")
+	done := make(chan error)
+	go func() {
+		done <- goredis.Client(ctx).BLPop(ctx, 0, "test-shutdown.test_shutdown_never").Err()
+	}()
+	time.Sleep(50 * time.Millisecond)
+
+	deadline, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	err := goredis.CloseAll(deadline)
+	c.Assert(errors.Is(err, context.DeadlineExceeded), Equals, true)
+	c.Assert(err, ErrorMatches, `(?s).*redis\[shutdown\]: close with 1 in-flight commands.*`)
+	c.Assert(<-done, NotNil)
+}
@@ -0,0 +1,237 @@
+package goredis_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/hecigo/goredis"
+	"github.com/hecigo/goutils"
+	. "gopkg.in/check.v1"
+)
+
+// The address of the plain Redis server behind the TLS-terminating stand-in.
+const tlsUpstreamAddr = "localhost:6379"
+
+type TLSSuite struct {
+	dir       string
+	tlsProxy  net.Listener
+	mtlsProxy net.Listener
+}
+
+var _ = Suite(&TLSSuite{})
+
+func (s *TLSSuite) SetUpSuite(c *C) {
+	fmt.Println("SetUpSuite > TLSSuite")
+	goutils.QuickLoad()
+
+	// issue a CA, a server certificate and a client certificate
+	s.dir = c.MkDir()
+	ca, caKey := writeCert(c, s.dir, "ca", nil, nil)
+	writeCert(c, s.dir, "server", ca, caKey)
+	writeCert(c, s.dir, "client", ca, caKey)
+
+	serverCert, err := tls.LoadX509KeyPair(filepath.Join(s.dir, "server.crt"), filepath.Join(s.dir, "server.key"))
+	c.Assert(err, IsNil)
+	pool := x509.NewCertPool()
+	pool.AddCert(ca)
+
+	// TLS only
+	s.tlsProxy = startTLSProxy(c, &tls.Config{
+		Certificates: []tls.Certificate{serverCert},
+	})
+
+	// mutual TLS
+	s.mtlsProxy = startTLSProxy(c, &tls.Config{
+		Certificates: []tls.Certificate{serverCert},
+		ClientCAs:    pool,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+	})
+}
+
+func (s *TLSSuite) TearDownSuite(c *C) {
+	s.tlsProxy.Close()
+	s.mtlsProxy.Close()
+}
+
+func (s *TLSSuite) TearDownTest(c *C) {
+	goredis.Close("tls")
+	for _, k := range []string{"URL", "TLS_ENABLE", "TLS_CA_CERT", "TLS_CERT", "TLS_KEY", "TLS_SERVER_NAME", "TLS_INSECURE_SKIP_VERIFY"} {
+		os.Unsetenv("REDISTLS_" + k)
+	}
+}
+
+// Test connecting to a TLS server with a custom CA bundle
+func (s *TLSSuite) TestTLS(c *C) {
+	os.Setenv("REDISTLS_URL", s.tlsProxy.Addr().String())
+	os.Setenv("REDISTLS_TLS_ENABLE", "true")
+	os.Setenv("REDISTLS_TLS_CA_CERT", filepath.Join(s.dir, "ca.crt"))
+	os.Setenv("REDISTLS_TLS_SERVER_NAME", "localhost")
+
+	err := goredis.Open("tls")
+	c.Assert(err, IsNil)
+	c.Assert(goredis.GetConfig(tlsCtx()).TLSEnabled, Equals, true)
+
+	err = goredis.Client(tlsCtx()).Ping(tlsCtx()).Err()
+	c.Assert(err, IsNil)
+}
+
+// Test connecting to a mutual TLS server with a client certificate
+func (s *TLSSuite) TestMutualTLS(c *C) {
+	os.Setenv("REDISTLS_URL", s.mtlsProxy.Addr().String())
+	os.Setenv("REDISTLS_TLS_ENABLE", "true")
+	os.Setenv("REDISTLS_TLS_CA_CERT", filepath.Join(s.dir, "ca.crt"))
+	os.Setenv("REDISTLS_TLS_CERT", filepath.Join(s.dir, "client.crt"))
+	os.Setenv("REDISTLS_TLS_KEY", filepath.Join(s.dir, "client.key"))
+	os.Setenv("REDISTLS_TLS_SERVER_NAME", "localhost")
+
+	err := goredis.Open("tls")
+	c.Assert(err, IsNil)
+
+	err = goredis.Client(tlsCtx()).Ping(tlsCtx()).Err()
+	c.Assert(err, IsNil)
+}
+
+// Test the mutual TLS server rejects a client without certificate
+func (s *TLSSuite) TestMutualTLSWithoutClientCert(c *C) {
+	os.Setenv("REDISTLS_URL", s.mtlsProxy.Addr().String())
+	os.Setenv("REDISTLS_TLS_ENABLE", "true")
+	os.Setenv("REDISTLS_TLS_CA_CERT", filepath.Join(s.dir, "ca.crt"))
+	os.Setenv("REDISTLS_TLS_SERVER_NAME", "localhost")
+
+	err := goredis.Open("tls")
+	c.Assert(err, IsNil)
+
+	err = goredis.Client(tlsCtx()).Ping(tlsCtx()).Err()
+	c.Assert(err, NotNil)
+}
+
+// Test skipping the server certificate verification
+func (s *TLSSuite) TestInsecureSkipVerify(c *C) {
+	os.Setenv("REDISTLS_URL", s.tlsProxy.Addr().String())
+	os.Setenv("REDISTLS_TLS_ENABLE", "true")
+	os.Setenv("REDISTLS_TLS_INSECURE_SKIP_VERIFY", "true")
+
+	err := goredis.Open("tls")
+	c.Assert(err, IsNil)
+
+	err = goredis.Client(tlsCtx()).Ping(tlsCtx()).Err()
+	c.Assert(err, IsNil)
+}
+
+// Test an unknown CA is rejected
+func (s *TLSSuite) TestUnknownCA(c *C) {
+	os.Setenv("REDISTLS_URL", s.tlsProxy.Addr().String())
+	os.Setenv("REDISTLS_TLS_ENABLE", "true")
+	os.Setenv("REDISTLS_TLS_SERVER_NAME", "localhost")
+
+	err := goredis.Open("tls")
+	c.Assert(err, IsNil)
+
+	err = goredis.Client(tlsCtx()).Ping(tlsCtx()).Err()
+	c.Assert(err, NotNil)
+}
+
+// Test the invalid TLS options are reported by Open
+func (s *TLSSuite) TestInvalidTLSConfig(c *C) {
+	os.Setenv("REDISTLS_TLS_ENABLE", "true")
+	os.Setenv("REDISTLS_TLS_CERT", filepath.Join(s.dir, "client.crt"))
+
+	err := goredis.Open("tls")
+	c.Assert(err, ErrorMatches, ".*both TLS cert and TLS key must be set.*")
+
+	os.Unsetenv("REDISTLS_TLS_CERT")
+	os.Setenv("REDISTLS_TLS_CA_CERT", filepath.Join(s.dir, "client.key"))
+	err = goredis.Open("tls")
+	c.Assert(err, ErrorMatches, ".*no valid certificate found.*")
+}
+
+func tlsCtx() context.Context {
+	return context.WithValue(context.Background(), goutils.CtxKey_ConnName, "tls")
+}
+
+// Start a TLS-terminating stand-in, which forwards the decrypted traffic to the plain Redis server.
+func startTLSProxy(c *C, cfg *tls.Config) net.Listener {
+	l, err := tls.Listen("tcp", "127.0.0.1:0", cfg)
+	c.Assert(err, IsNil)
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+
+			go func() {
+				defer conn.Close()
+
+				// complete the handshake before dialing upstream, so that rejected clients never reach Redis
+				if err := conn.(*tls.Conn).Handshake(); err != nil {
+					return
+				}
+
+				upstream, err := net.Dial("tcp", tlsUpstreamAddr)
+				if err != nil {
+					return
+				}
+				defer upstream.Close()
+
+				go io.Copy(upstream, conn)
+				io.Copy(conn, upstream)
+			}()
+		}
+	}()
+
+	return l
+}
+
+// Issue a certificate signed by the parent, or a self-signed CA if parent is nil.
+// The certificate and key are written to `<dir>/<name>.crt` and `<dir>/<name>.key`.
+func writeCert(c *C, dir string, name string, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	c.Assert(err, IsNil)
+
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		DNSNames:     []string{"localhost"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	if parent == nil {
+		tmpl.IsCA = true
+		tmpl.BasicConstraintsValid = true
+		tmpl.KeyUsage |= x509.KeyUsageCertSign
+		parent, parentKey = tmpl, key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, parent, &key.PublicKey, parentKey)
+	c.Assert(err, IsNil)
+	cert, err := x509.ParseCertificate(der)
+	c.Assert(err, IsNil)
+
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	c.Assert(err, IsNil)
+
+	err = os.WriteFile(filepath.Join(dir, name+".crt"), pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600)
+	c.Assert(err, IsNil)
+	err = os.WriteFile(filepath.Join(dir, name+".key"), pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600)
+	c.Assert(err, IsNil)
+
+	return cert, key
+}
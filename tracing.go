@@ -0,0 +1,178 @@
+package goredis
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/hecigo/goutils"
+	apmgoredis "go.elastic.co/apm/module/apmgoredisv8/v2"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// The tracing backend of a connection.
+type Tracing string
+
+const (
+	Tracing_Auto Tracing = ""     // env `REDIS_TRACING`, or [Tracing_APM] if env `ELASTIC_APM_ENABLE` is true (default)
+	Tracing_APM  Tracing = "apm"  // Elastic APM, [apmgoredis.NewHook]
+	Tracing_OTel Tracing = "otel" // OpenTelemetry, with the global tracer provider
+	Tracing_None Tracing = "none" // no tracing
+)
+
+// The name of the OpenTelemetry tracer.
+const tracerName = "github.com/hecigo/goredis"
+
+// Parse the tracing backend from string.
+func ParseTracing(s string) (Tracing, error) {
+	switch t := Tracing(strings.ToLower(strings.TrimSpace(s))); t {
+	case Tracing_Auto, Tracing_APM, Tracing_OTel, Tracing_None:
+		return t, nil
+	default:
+		return Tracing_Auto, fmt.Errorf("unknown tracing `%s`", s)
+	}
+}
+
+// Resolve [Tracing_Auto] from env, the invalid value falls back to the legacy env `ELASTIC_APM_ENABLE`.
+func autoTracing() Tracing {
+	t, err := ParseTracing(goutils.Env("REDIS_TRACING", ""))
+	if err != nil {
+		goutils.Warnf("REDIS_TRACING: %s", err)
+	}
+	if t != Tracing_Auto {
+		return t
+	}
+	if goutils.Env("ELASTIC_APM_ENABLE", true) {
+		return Tracing_APM
+	}
+	return Tracing_None
+}
+
+// Returns the hook of the tracing backend, or nil if tracing is disabled.
+func (cfg *Config) tracingHook() redis.Hook {
+	switch cfg.Tracing {
+	case Tracing_APM:
+		return apmgoredis.NewHook()
+	case Tracing_OTel:
+		return &otelHook{attrs: cfg.spanAttributes()}
+	default:
+		return nil
+	}
+}
+
+// Returns the attributes of every span of the connection.
+func (cfg *Config) spanAttributes() []attribute.KeyValue {
+	return []attribute.KeyValue{
+		attribute.String("db.system", "redis"),
+		attribute.Int("db.redis.database_index", cfg.DB),
+		attribute.String("db.redis.connection_name", cfg.ConnectionName),
+		attribute.String("db.redis.key_prefix", cfg.KeyPrefix),
+	}
+}
+
+// The [redis.Hook] to create an OpenTelemetry span per command and per pipeline.
+type otelHook struct {
+	attrs []attribute.KeyValue
+}
+
+func (h *otelHook) BeforeProcess(ctx context.Context, cmd redis.Cmder) (context.Context, error) {
+	ctx, _ = otel.Tracer(tracerName).Start(ctx, cmd.FullName(),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(h.attrs...),
+		trace.WithAttributes(attribute.String("db.statement", redactStatement(cmd))),
+	)
+	return ctx, nil
+}
+
+func (h *otelHook) AfterProcess(ctx context.Context, cmd redis.Cmder) error {
+	span := trace.SpanFromContext(ctx)
+	endSpan(span, cmd.Err())
+	return nil
+}
+
+func (h *otelHook) BeforeProcessPipeline(ctx context.Context, cmds []redis.Cmder) (context.Context, error) {
+	statements := make([]string, len(cmds))
+	for i, cmd := range cmds {
+		statements[i] = redactStatement(cmd)
+	}
+
+	ctx, _ = otel.Tracer(tracerName).Start(ctx, "pipeline",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(h.attrs...),
+		trace.WithAttributes(
+			attribute.String("db.statement", strings.Join(statements, "\n")),
+			attribute.Int("db.redis.num_cmd", len(cmds)),
+		),
+	)
+	return ctx, nil
+}
+
+func (h *otelHook) AfterProcessPipeline(ctx context.Context, cmds []redis.Cmder) error {
+	var err error
+	for _, cmd := range cmds {
+		if cmdErr := cmd.Err(); cmdErr != nil && cmdErr != redis.Nil {
+			err = cmdErr
+			break
+		}
+	}
+	endSpan(trace.SpanFromContext(ctx), err)
+	return nil
+}
+
+// Record the error on the span, then end it. [redis.Nil] is not an error, it means the key does not exist.
+func endSpan(span trace.Span, err error) {
+	if err != nil && err != redis.Nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// Returns the statement of the command with the values redacted. Only the command name and the first argument,
+// which is usually the key, are kept, e.g. `set key ? ? ?` for `SET key value EX 60`.
+func redactStatement(cmd redis.Cmder) string {
+	args := cmd.Args()
+	if len(args) == 0 {
+		return ""
+	}
+
+	parts := make([]string, len(args))
+	parts[0] = cmd.Name()
+	for i := 1; i < len(args); i++ {
+		if i == 1 {
+			parts[i] = fmt.Sprint(args[i])
+		} else {
+			parts[i] = "?"
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// A span which does nothing, returned by [startSpan] if the connection is not traced by OpenTelemetry.
+var noopSpan = trace.SpanFromContext(context.Background())
+
+// Start a parent span of the commands with a meaningful name, such as `RankingBoard.Top`,
+// if the connection is traced by OpenTelemetry. Otherwise, returns a no-op span, so that the caller can always end it.
+func startSpan(ctx context.Context, spanName string, key string) (context.Context, trace.Span) {
+	if ctx == nil {
+		return ctx, noopSpan
+	}
+
+	name, err := ctxConnName("default", ctx)
+	if err != nil {
+		return ctx, noopSpan
+	}
+	conn := reg.conn(name)
+	if conn == nil || conn.config.Tracing != Tracing_OTel {
+		return ctx, noopSpan
+	}
+
+	return otel.Tracer(tracerName).Start(ctx, spanName,
+		trace.WithAttributes(conn.config.spanAttributes()...),
+		trace.WithAttributes(attribute.String("db.redis.key", key)),
+	)
+}
@@ -0,0 +1,122 @@
+package goredis_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/hecigo/goredis"
+	"github.com/hecigo/goutils"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	. "gopkg.in/check.v1"
+)
+
+type TracingSuite struct {
+	recorder *tracetest.SpanRecorder
+	provider trace.TracerProvider
+}
+
+var _ = Suite(&TracingSuite{})
+
+func (s *TracingSuite) SetUpSuite(c *C) {
+	fmt.Println("SetUpSuite > TracingSuite")
+	goutils.QuickLoad()
+
+	s.provider = otel.GetTracerProvider()
+	s.recorder = tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(s.recorder)))
+
+	os.Setenv("REDISTRACING_TRACING", "otel")
+	os.Setenv("REDISTRACING_KEY_PREFIX", "test-tracing")
+	c.Assert(goredis.Open("tracing"), IsNil)
+}
+
+func (s *TracingSuite) TearDownSuite(c *C) {
+	goredis.Close("tracing")
+	os.Unsetenv("REDISTRACING_TRACING")
+	os.Unsetenv("REDISTRACING_KEY_PREFIX")
+	otel.SetTracerProvider(s.provider)
+}
+
+// Test the tracing backend is loaded from env and validated
+func (s *TracingSuite) TestConfig(c *C) {
+	c.Assert(goredis.GetConfig(tracingCtx()).Tracing, Equals, goredis.Tracing_OTel)
+
+	os.Setenv("REDISTRACING2_TRACING", "zipkin")
+	defer os.Unsetenv("REDISTRACING2_TRACING")
+	c.Assert(goredis.Open("tracing2"), ErrorMatches, ".*unknown tracing `zipkin`.*")
+
+	c.Assert(goredis.OpenWithConfig(&goredis.Config{ConnectionName: "tracing2", Tracing: "zipkin"}), ErrorMatches, ".*unknown tracing `zipkin`.*")
+}
+
+// Test the commands are traced with the redacted statements
+func (s *TracingSuite) TestCommands(c *C) {
+	ctx := tracingCtx()
+	c.Assert(goredis.Set(ctx, "test_tracing", "secret"), IsNil)
+	_, err := goredis.Client(ctx).Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Get(ctx, "test-tracing.test_tracing")
+		pipe.Get(ctx, "test-tracing.test_tracing_missing")
+		return nil
+	})
+	c.Assert(err, Equals, redis.Nil)
+
+	set := s.span(c, "set")
+	c.Assert(set.SpanKind(), Equals, trace.SpanKindClient)
+	attrs := spanAttributes(set)
+	c.Assert(attrs["db.system"], Equals, "redis")
+	c.Assert(attrs["db.statement"], Equals, "set test-tracing.test_tracing ?")
+	c.Assert(attrs["db.redis.connection_name"], Equals, "tracing")
+	c.Assert(attrs["db.redis.key_prefix"], Equals, "test-tracing")
+
+	pipeline := s.span(c, "pipeline")
+	attrs = spanAttributes(pipeline)
+	c.Assert(attrs["db.redis.num_cmd"], Equals, "2")
+	c.Assert(attrs["db.statement"], Matches, "(?s).*get test-tracing.test_tracing.*")
+}
+
+// Test the ranking board and the cache operations are the parents of their commands
+func (s *TracingSuite) TestParentSpans(c *C) {
+	ctx := tracingCtx()
+	board := goredis.GetRankingBoard(ctx, "test_tracing_ranking")
+	c.Assert(board.UpsertMulti(map[string]float64{"member1": 1, "member2": 2}), IsNil)
+	_, err := board.Score("member1")
+	c.Assert(err, IsNil)
+
+	upsert := s.span(c, "RankingBoard.UpsertMulti")
+	c.Assert(spanAttributes(upsert)["db.redis.key"], Equals, "test-tracing.test_tracing_ranking")
+	c.Assert(s.span(c, "pipeline").Parent().SpanID(), Equals, upsert.SpanContext().SpanID())
+	c.Assert(s.span(c, "zscore").Parent().SpanID(), Equals, s.span(c, "RankingBoard.Score").SpanContext().SpanID())
+
+	c.Assert(goredis.EnableCache("tracing"), IsNil)
+	c.Assert(goredis.SetCache(ctx, "test_tracing_cache", "value", time.Minute), IsNil)
+	c.Assert(s.span(c, "set").Parent().SpanID(), Equals, s.span(c, "Cache.Set").SpanContext().SpanID())
+}
+
+// Returns the last ended span with the name
+func (s *TracingSuite) span(c *C, name string) sdktrace.ReadOnlySpan {
+	spans := s.recorder.Ended()
+	for i := len(spans) - 1; i >= 0; i-- {
+		if spans[i].Name() == name {
+			return spans[i]
+		}
+	}
+	c.Fatalf("span `%s` not found", name)
+	return nil
+}
+
+func spanAttributes(span sdktrace.ReadOnlySpan) map[string]string {
+	m := make(map[string]string)
+	for _, kv := range span.Attributes() {
+		m[string(kv.Key)] = kv.Value.Emit()
+	}
+	return m
+}
+
+func tracingCtx() context.Context {
+	return context.WithValue(context.Background(), goutils.CtxKey_ConnName, "tracing")
+}